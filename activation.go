@@ -0,0 +1,92 @@
+package main
+import (
+	"math"
+)
+
+type Activation int
+
+const (
+	Sigmoid Activation = iota
+	None               // identity, for raw linear outputs
+	ReLU
+	Tanh
+	// PReLU is ReLU with a learned slope for negative inputs, one per
+	// hidden unit, starting at preluInitSlope; hidden layers only
+	PReLU
+)
+
+const preluInitSlope = 0.25
+
+func (a Activation) apply(v float64) float64 {
+	switch a {
+	case None:
+		return v
+	case ReLU:
+		return math.Max(0, v)
+	case Tanh:
+		return math.Tanh(v)
+	case PReLU:
+		if v > 0 {
+			return v
+		}
+		return preluInitSlope * v
+	default:
+		return sigmoid(v)
+	}
+}
+
+// prime is the slope backpropagate multiplies into a layer's error. Like the
+// rest of the training code it is evaluated on the layer's activations.
+func (a Activation) prime(v float64) float64 {
+	switch a {
+	case None:
+		return 1
+	case ReLU:
+		if v > 0 {
+			return 1
+		}
+		return 0
+	case Tanh:
+		return 1 - v*v
+	case PReLU:
+		if v > 0 {
+			return 1
+		}
+		return preluInitSlope
+	default:
+		return v * (1 - v)
+	}
+}
+
+// goExpr renders the activation of expr as Go source for GenerateGoInference.
+func (a Activation) goExpr(expr string) string {
+	switch a {
+	case None:
+		return expr
+	case ReLU:
+		return "math.Max(0, " + expr + ")"
+	case Tanh:
+		return "math.Tanh(" + expr + ")"
+	case PReLU:
+		// the generated file declares prelu and the learned hiddenSlopes
+		return "prelu(" + expr + ", hiddenSlopes[j])"
+	default:
+		return "sigmoid(" + expr + ")"
+	}
+}
+
+// name is the lower-case name ExportWeightsJSON records the activation by.
+func (a Activation) name() string {
+	switch a {
+	case None:
+		return "none"
+	case ReLU:
+		return "relu"
+	case Tanh:
+		return "tanh"
+	case PReLU:
+		return "prelu"
+	default:
+		return "sigmoid"
+	}
+}
@@ -0,0 +1,154 @@
+package main
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+var inferenceTemplate = template.Must(template.New("inference").Parse(`// Code generated by egnn. DO NOT EDIT.
+
+package {{.Package}}
+
+import "math"
+
+const (
+	numInputs  = {{.Inputs}}
+	numHidden  = {{.Hidden}}
+	numOutputs = {{.Outputs}}
+)
+{{if .Scaled}}
+var scalerOffset = [numInputs]float64{{.ScalerOffset}}
+
+var scalerScale = [numInputs]float64{{.ScalerScale}}
+{{end}}
+var wHidden = [numInputs][numHidden]float64{{.WHidden}}
+
+var bHidden = [numHidden]float64{{.BHidden}}
+
+var wOut = [numHidden][numOutputs]float64{{.WOut}}
+
+var bOut = [numOutputs]float64{{.BOut}}
+{{if .Slopes}}
+var hiddenSlopes = [numHidden]float64{{.Slopes}}
+
+func prelu(x, slope float64) float64 {
+	if x > 0 {
+		return x
+	}
+	return slope * x
+}
+{{end}}
+func sigmoid(x float64) float64 {
+	return 1.0 / (1.0 + math.Exp(-x))
+}
+
+// Predict maps one encoded input row to the network's outputs.
+func Predict(input []float64) []float64 {
+	if len(input) != numInputs {
+		panic("{{.Package}}: wrong number of inputs")
+	}
+
+	var hidden [numHidden]float64
+	for j := 0; j < numHidden; j++ {
+		sum := bHidden[j]
+		for i := 0; i < numInputs; i++ {
+			v := input[i]
+{{- if .Scaled}}
+			v = (v - scalerOffset[i]) / scalerScale[i]
+{{- end}}
+			sum += v * wHidden[i][j]
+		}
+		hidden[j] = {{.HiddenExpr}}
+	}
+
+	output := make([]float64, numOutputs)
+	for k := 0; k < numOutputs; k++ {
+		sum := bOut[k]
+		for j := 0; j < numHidden; j++ {
+			sum += hidden[j] * wOut[j][k]
+		}
+		output[k] = {{.OutputExpr}}
+	}
+
+	return output
+}
+`))
+
+// GenerateGoInference writes a standalone Go source file for package pkgName
+// that reproduces Predict using only the standard library. The weights are
+// embedded as package-level arrays.
+func (nn *NeuralNet) GenerateGoInference(pkgName string, w io.Writer) error {
+	if nn.wHidden == nil || nn.wOut == nil {
+		return fmt.Errorf("%w: the supplied weights are empty", ErrNotTrained)
+	}
+	if !token.IsIdentifier(pkgName) {
+		return fmt.Errorf("%q is not a valid package name", pkgName)
+	}
+
+	data := struct {
+		Package                 string
+		Inputs, Hidden, Outputs int
+		Scaled                  bool
+		ScalerOffset            string
+		ScalerScale             string
+		WHidden, BHidden        string
+		WOut, BOut              string
+		Slopes                  string
+		HiddenExpr, OutputExpr  string
+	}{
+		Package:    pkgName,
+		Inputs:     nn.config.InputNeurons,
+		Hidden:     nn.config.HiddenNeurons,
+		Outputs:    nn.config.OutputNeurons,
+		WHidden:    goMatrixLiteral(nn.wHidden),
+		BHidden:    goSliceLiteral(mat.Row(nil, 0, nn.bHidden)),
+		WOut:       goMatrixLiteral(nn.wOut),
+		BOut:       goSliceLiteral(mat.Row(nil, 0, nn.bOut)),
+		HiddenExpr: nn.hiddenActivation().goExpr("sum"),
+		OutputExpr: nn.config.OutputActivation.goExpr("sum"),
+	}
+	if nn.slopes != nil {
+		data.Slopes = goSliceLiteral(mat.Row(nil, 0, nn.slopes))
+	}
+	if nn.scaler != nil {
+		data.Scaled = true
+		data.ScalerOffset = goSliceLiteral(nn.scaler.Offset)
+		data.ScalerScale = goSliceLiteral(nn.scaler.Scale)
+	}
+
+	buf := new(bytes.Buffer)
+	if err := inferenceTemplate.Execute(buf, data); err != nil {
+		return err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(src)
+	return err
+}
+
+func goSliceLiteral(values []float64) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func goMatrixLiteral(m *mat.Dense) string {
+	numRows, _ := m.Dims()
+	rows := make([]string, numRows)
+	for i := range rows {
+		rows[i] = "\t" + goSliceLiteral(mat.Row(nil, i, m)) + ",\n"
+	}
+	return "{\n" + strings.Join(rows, "") + "}"
+}
@@ -1,171 +1,1338 @@
 package main
 import (
+	"math"
 	"math/rand"
+	"sync"
+	"sync/atomic"
 	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sort"
 	"time"
+	"gonum.org/v1/gonum/floats"
 	"gonum.org/v1/gonum/mat"
 )
 
 type NetConfig struct {
-	InputNeurons   int
-	OutputNeurons  int
-	HiddenNeurons  int
-	NumEpochs      int
-	LearningRate   float64
+	InputNeurons  int
+	OutputNeurons int
+	HiddenNeurons int
+	NumEpochs     int
+	// BatchSize is the rows per update, 0 trains on the full set each step.
+	// Each epoch shuffles the rows once and cuts the order into consecutive
+	// batches, so every row is used exactly once per epoch
+	BatchSize             int
+	LearningRate          float64
+	LayerLRMultipliers    []float64 // per-layer LearningRate scale, hidden then output; nil means 1
+	Normalization         Normalization
+	Optimizer             OptimizerConfig
+	Seed                  int64 // 0 seeds from the clock
+	MixedPrecision        bool  // float32 matmuls during training, float64 weights
+	TransposedWOut        bool  // also keep wOut in transposed layout for the output-layer product, faster for tall batches with few outputs
+	CheckpointEvery       int   // save every N epochs, 0 disables
+	CheckpointPath        string
+	Undersample           bool      // balance classes each epoch by dropping majority rows
+	ValidationSplit       float64   // fraction of rows held out for validation loss
+	StratifiedSplit       bool      // hold out ValidationSplit of each class separately
+	PlateauPatience       int       // epochs without improvement before reducing LR
+	PlateauFactor         float64   // LR multiplier on plateau, 0 means 0.1
+	EarlyStoppingPatience int       // epochs without improvement before stopping
+	MinDelta              float64   // smallest loss decrease counted as improvement
+	OutputLossWeights     []float64 // per-output error scale, nil weighs all equally
+	// InitialInputWeights overrides the random init of wHidden rows, keyed by
+	// input column; see NeuralInterface.EmbeddingRows
+	InitialInputWeights map[int][]float64
+	FrozenInputs        []int   // input columns whose wHidden rows training leaves as they are
+	OutputOnlyEpochs    int     // initial epochs that train only wOut and bOut
+	AccuracySmoothing   float64 // EMA factor for SmoothedAccuracy, 0 means 0.9
+	OutputActivation    Activation
+	HiddenActivations   []Activation // one per hidden layer, or a single entry for all; nil means Sigmoid
+	// OutputQuantiles trains column j with the pinball loss for quantile
+	// OutputQuantiles[j]; zero entries, or a nil slice, keep squared error
+	OutputQuantiles []float64
+	// LossEpsilon makes squared error insensitive to residuals within
+	// ±LossEpsilon: they cost nothing and larger ones only for the excess,
+	// e.g. for targets with known measurement noise
+	LossEpsilon float64
+	// CrossEntropy trains the outputs as the logits of a single categorical
+	// distribution with softmax cross-entropy instead of squared error.
+	// OutputActivation must be None; Predict still returns the logits
+	CrossEntropy bool
+	// InputDropout is the chance that training drops an input column from a
+	// batch, zeroing it and scaling the kept columns by 1/(1-InputDropout);
+	// Predict always sees every column
+	InputDropout float64
+	// WarmRestartEpochs turns on cosine annealing with warm restarts
+	// (SGDR): the rate falls from LearningRate along a half cosine towards
+	// 0 over a cycle of this many epochs and then jumps back, each cycle
+	// RestartMultiplier times as long as the last (0 means 1)
+	WarmRestartEpochs int
+	RestartMultiplier float64
+	// AdaptiveBatchMax, if above BatchSize, grows the batch size between
+	// epochs towards the gradient noise scale measured over the last
+	// epoch's batches, at most doubling it each epoch, never shrinking it
+	// and stopping at AdaptiveBatchMax. It needs a BatchSize and the
+	// default Sampler. Gradients are summed over a batch, so except with
+	// Adam the epoch's learning rate is scaled by BatchSize over the grown
+	// size: a larger batch only cuts the noise of each step, not its size
+	AdaptiveBatchMax int
+	// Augment, if set, rewrites the (normalized) inputs at the start of each
+	// epoch; the change is not carried over to later epochs
+	Augment func(x *mat.Dense, epoch int) *mat.Dense
+	// OnBatch, if set, is called before each training step with the epoch,
+	// the batch's position in it and the indices of its rows within the
+	// training data left after any validation split
+	OnBatch func(epoch, batch int, rows []int)
+	// Sampler, if set, chooses each epoch's batches instead of BatchSize,
+	// e.g. for curriculum or hard-example schedules. It is not saved with
+	// the net
+	Sampler Sampler
+	// ProgressWriter, if set, receives a line with the epoch, loss and
+	// estimated time left at most once per ProgressInterval (0 means a
+	// second) and after the last epoch. It is not saved with the net
+	ProgressWriter   io.Writer
+	ProgressInterval time.Duration
+	// RestoreBestWeights installs, once training ends, the weights of the
+	// epoch with the lowest validation loss (training loss without a
+	// split) in place of the last epoch's; see BestWeights
+	RestoreBestWeights bool
 }
 
 type NeuralNet struct {
-	config   NetConfig
-	wHidden  *mat.Dense
-	bHidden  *mat.Dense
-	wOut     *mat.Dense
-	bOut     *mat.Dense
+	config  NetConfig
+	wHidden *mat.Dense
+	bHidden *mat.Dense
+	wOut    *mat.Dense
+	bOut    *mat.Dense
+	// slopes are PReLU's learned negative slopes, 1×HiddenNeurons; nil for
+	// other hidden activations
+	slopes *mat.Dense
+	scaler *scaler
+	epoch   int
+	rng     *rand.Rand
+	report  TrainingReport
+	best    *bestWeights
+	// batchSize is the batch size AdaptiveBatchMax has grown to, 0 until
+	// it first grows
+	batchSize int
+	// optimizer state, per parameter in step order: the Momentum velocity
+	// or Adam's first moment, Adam's second moment and its step count
+	velocity     []*mat.Dense
+	secondMoment []*mat.Dense
+	adamSteps    int
+	// lr is the learning rate PlateauPatience has reduced to, 0 until it
+	// first does
+	lr float64
+	// rngSource counts the draws from rng so a checkpoint can record its
+	// position
+	rngSource *countingSource
+
+	scratchMu sync.Mutex
+	scratch   *predictScratch
+	// wOutT mirrors wOut in transposed layout for TransposedWOut; see
+	// outWeights
+	wOutT atomic.Pointer[transposedWeights]
 }
 
 func NewNet(conf NetConfig) *NeuralNet {
 	return &NeuralNet{config: conf}
 }
 
-func (nn *NeuralNet) Train(x, y *mat.Dense) error {
-	randSource := rand.NewSource(time.Now().UnixNano())
-	randGen := rand.New(randSource)
+// NewNetFromInterface builds a net whose input and output layers match the
+// encoded width of ni. Zero neuron counts in conf are filled in from the
+// schema; non-zero counts must agree with it.
+func NewNetFromInterface(ni *NeuralInterface, conf NetConfig) (*NeuralNet, error) {
+	inputs, outputs := ni.InputWidth(), ni.OutputWidth()
 
-	wHidden := mat.NewDense(nn.config.InputNeurons, nn.config.HiddenNeurons, nil)
-	bHidden := mat.NewDense(1, nn.config.HiddenNeurons, nil)
+	if conf.InputNeurons == 0 {
+		conf.InputNeurons = inputs
+	}
+	if conf.OutputNeurons == 0 {
+		conf.OutputNeurons = outputs
+	}
+	if conf.InputNeurons != inputs {
+		return nil, fmt.Errorf("%w: schema encodes %d input columns but InputNeurons is %d", ErrDimensionMismatch, inputs, conf.InputNeurons)
+	}
+	if conf.OutputNeurons != outputs {
+		return nil, fmt.Errorf("%w: schema encodes %d output columns but OutputNeurons is %d", ErrDimensionMismatch, outputs, conf.OutputNeurons)
+	}
+	if conf.OutputQuantiles == nil {
+		conf.OutputQuantiles = ni.OutputQuantiles()
+	}
 
-	wOut := mat.NewDense(nn.config.HiddenNeurons, nn.config.OutputNeurons, nil)
-	bOut := mat.NewDense(1, nn.config.OutputNeurons, nil)
+	return NewNet(conf), nil
+}
 
+// Fit is the one-call path from raw data to a trained net. It fits the
+// Categorical categories and the Continuous input and output ranges of ni to
+// data (modifying ni), encodes data, sizes a net from the schema and conf,
+// and trains it. Fit the schema on training data only; evaluate on held-out
+// data with Report.
+func Fit(ni *NeuralInterface, data []TrainingDatum, conf NetConfig) (*NeuralNet, TrainingReport, error) {
+	ni.FitCategories(data)
+	ni.FitContinuousRanges(data)
+	ni.FitOutputRanges(data)
 
-	wHiddenRaw := wHidden.RawMatrix().Data
-	bHiddenRaw := bHidden.RawMatrix().Data
-	wOutRaw := wOut.RawMatrix().Data
-	bOutRaw := bOut.RawMatrix().Data
+	x, y, err := ni.EncodeBatch(data)
+	if err != nil {
+		return nil, TrainingReport{}, err
+	}
+	nn, err := NewNetFromInterface(ni, conf)
+	if err != nil {
+		return nil, TrainingReport{}, err
+	}
+	if err := nn.Train(x, y); err != nil {
+		return nil, TrainingReport{}, err
+	}
 
-	for _, param := range [][]float64{
-		wHiddenRaw,
-		bHiddenRaw,
-		wOutRaw,
+	return nn, nn.TrainingReport(), nil
+}
 
-		bOutRaw,
-	} {
-		for i := range param {
-			param[i] = randGen.Float64()
-		}
+// FitAutoencoder is Fit for anomaly detection: it fits the input schema of
+// ni to inputs, replaces the output schema with a mirror of the input
+// columns (see MirrorOutputs) and trains the net to reproduce its encoded
+// inputs under squared error. Score new rows with ReconstructionError.
+func FitAutoencoder(ni *NeuralInterface, inputs []map[string]interface{}, conf NetConfig) (*NeuralNet, TrainingReport, error) {
+	if len(inputs) == 0 {
+		return nil, TrainingReport{}, fmt.Errorf("%w: no data to encode", ErrEmptyData)
+	}
 
+	data := make([]TrainingDatum, len(inputs))
+	for i, input := range inputs {
+		data[i] = TrainingDatum{Inputs: input}
 	}
+	ni.FitCategories(data)
+	ni.FitContinuousRanges(data)
+	ni.MirrorOutputs()
 
-	output := new(mat.Dense)
+	x, err := ni.encodeInputs(inputs)
+	if err != nil {
+		return nil, TrainingReport{}, err
+	}
+	nn, err := NewNetFromInterface(ni, conf)
+	if err != nil {
+		return nil, TrainingReport{}, err
+	}
+	if err := nn.Train(x, x); err != nil {
+		return nil, TrainingReport{}, err
+	}
+
+	return nn, nn.TrainingReport(), nil
+}
 
-	if err := nn.backpropagate(x, y, wHidden, bHidden, wOut, bOut, output); err != nil {
+func (nn *NeuralNet) Train(x, y *mat.Dense) error {
+	if err := nn.checkTrainingData(x, y); err != nil {
 		return err
 	}
 
+	seed := nn.config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	// the training stream sits just below the per-layer streams
+	nn.seedTrainRand(seed - 1)
+
+	x, y, xVal, yVal := nn.splitValidation(x, y)
+
+	var s *scaler
+	if nn.config.Normalization != NoNormalization {
+		s = fitScaler(nn.config.Normalization, x)
+		x = s.transform(x)
+		if xVal != nil {
+			xVal = s.transform(xVal)
+		}
+	}
+
+	// each layer draws from its own stream so changing one layer's shape
+	// leaves the initialization of the others untouched
+	wHidden, bHidden := initLayer(seed+0, nn.config.InputNeurons, nn.config.HiddenNeurons)
+	wOut, bOut := initLayer(seed+1, nn.config.HiddenNeurons, nn.config.OutputNeurons)
+	for row, weights := range nn.config.InitialInputWeights {
+		if row < 0 || row >= nn.config.InputNeurons {
+			return fmt.Errorf("%w: initial weights given for input column %d of %d", ErrDimensionMismatch, row, nn.config.InputNeurons)
+		}
+		if len(weights) != nn.config.HiddenNeurons {
+			return fmt.Errorf("%w: initial weights for input column %d have length %d, expected %d", ErrDimensionMismatch, row, len(weights), nn.config.HiddenNeurons)
+		}
+		wHidden.SetRow(row, weights)
+	}
+
+	// the net holds the live matrices while training so checkpoints see
+	// the current weights
 	nn.wHidden = wHidden
 	nn.bHidden = bHidden
 	nn.wOut = wOut
 
 	nn.bOut = bOut
+	nn.slopes = nil
+	if nn.hiddenActivation() == PReLU {
+		nn.slopes = mat.NewDense(1, nn.config.HiddenNeurons, nil)
+		nn.slopes.Apply(func(_, _ int, _ float64) float64 { return preluInitSlope }, nn.slopes)
+	}
+	nn.scaler = s
+	nn.epoch = 0
+	nn.report = TrainingReport{}
+	nn.best = nil
+	nn.batchSize = 0
+	nn.lr = 0
+	nn.resetOptimizer()
 
-	return nil
+	return nn.backpropagate(x, y, xVal, yVal, wHidden, bHidden, wOut, bOut)
 }
 
-func (nn *NeuralNet) backpropagate(x, y, wHidden, bHidden, wOut, bOut, output *mat.Dense) error {
-	for i := 0; i < nn.config.NumEpochs; i++ {
-		hiddenLayerInput := new(mat.Dense)
-		hiddenLayerInput.Mul(x, wHidden)
-		addBHidden := func(_, col int, v float64) float64 { return v + bHidden.At(0, col) }
-		hiddenLayerInput.Apply(addBHidden, hiddenLayerInput)
+// TrainMasked is Train for partially labelled targets: cells of y where
+// mask, of the same shape, is zero are treated as unknown and contribute
+// neither loss nor gradient. Passing NaN in those cells of y to Train does
+// the same.
+func (nn *NeuralNet) TrainMasked(x, y, mask *mat.Dense) error {
+	if mask == nil || y == nil || y.IsEmpty() {
+		return nn.Train(x, y)
+	}
+	yr, yc := y.Dims()
+	if mr, mc := mask.Dims(); mr != yr || mc != yc {
+		return fmt.Errorf("%w: mask is %dx%d but targets are %dx%d", ErrDimensionMismatch, mr, mc, yr, yc)
+	}
 
-		hiddenLayerActivations := new(mat.Dense)
-		applySigmoid := func(_, _ int, v float64) float64 { return sigmoid(v) }
-		hiddenLayerActivations.Apply(applySigmoid, hiddenLayerInput)
+	masked := mat.DenseCopyOf(y)
+	masked.Apply(func(i, j int, v float64) float64 {
+		if mask.At(i, j) == 0 {
+			return math.NaN()
+		}
+		return v
+	}, masked)
 
-		outputLayerInput := new(mat.Dense)
-		outputLayerInput.Mul(hiddenLayerActivations, wOut)
-		addBOut := func(_, col int, v float64) float64 { return v + bOut.At(0, col) }
-		outputLayerInput.Apply(addBOut, outputLayerInput)
-		output.Apply(applySigmoid, outputLayerInput)
+	return nn.Train(x, masked)
+}
 
+// DistillFrom trains the net, like Train, to mimic teacher on x: the
+// targets are the teacher's predictions at temperature, softened by
+// PredictProba (and a softmax when the teacher was trained with
+// CrossEntropy), mixed with the true labels y as
+// (1-labelWeight)·soft + labelWeight·y. Both the squared error and the
+// cross-entropy are linear in the target, so this trains on the weighted
+// sum of a distillation and a supervised loss. y may be nil when
+// labelWeight is 0; missing (NaN) labels fall back to the soft target.
+// The student is trained at temperature 1.
+func (nn *NeuralNet) DistillFrom(teacher *NeuralNet, temperature float64, x, y *mat.Dense, labelWeight float64) error {
+	if labelWeight < 0 || labelWeight > 1 {
+		return fmt.Errorf("label weight %v is outside [0, 1]", labelWeight)
+	}
+	if teacher.config.InputNeurons != nn.config.InputNeurons || teacher.config.OutputNeurons != nn.config.OutputNeurons {
+		return fmt.Errorf("%w: teacher is %dx%d but the net is %dx%d", ErrDimensionMismatch,
+			teacher.config.InputNeurons, teacher.config.OutputNeurons, nn.config.InputNeurons, nn.config.OutputNeurons)
+	}
 
-		networkError := new(mat.Dense)
-		networkError.Sub(y, output)
+	soft, err := teacher.PredictProba(x, temperature)
+	if err != nil {
+		return fmt.Errorf("teacher: %w", err)
+	}
+	if teacher.config.CrossEntropy {
+		numRows, _ := soft.Dims()
+		for i := 0; i < numRows; i++ {
+			soft.SetRow(i, softmax(soft.RawRowView(i)))
+		}
+	}
+	if labelWeight == 0 {
+		return nn.Train(x, soft)
+	}
 
-		slopeOutputLayer := new(mat.Dense)
-		applySigmoidPrime := func(_, _ int, v float64) float64 { return sigmoidPrime(v) }
-		slopeOutputLayer.Apply(applySigmoidPrime, output)
+	if y == nil {
+		return fmt.Errorf("%w: a label weight of %v needs labels", ErrEmptyData, labelWeight)
+	}
+	sr, sc := soft.Dims()
+	if yr, yc := y.Dims(); yr != sr || yc != sc {
+		return fmt.Errorf("%w: labels are %dx%d but the teacher predicts %dx%d", ErrDimensionMismatch, yr, yc, sr, sc)
+	}
+	soft.Apply(func(i, j int, v float64) float64 {
+		if label := y.At(i, j); !math.IsNaN(label) {
+			return (1-labelWeight)*v + labelWeight*label
+		}
+		return v
+	}, soft)
 
-		slopeHiddenLayer := new(mat.Dense)
-		slopeHiddenLayer.Apply(applySigmoidPrime, hiddenLayerActivations)
+	return nn.Train(x, soft)
+}
 
+// FindLR runs a learning-rate range test on a fresh copy of the net,
+// leaving nn itself untouched: it initializes the copy as Train would,
+// then takes steps optimizer steps over the batches of x and y the training
+// Sampler picks (BatchSize rows, or all of them, by default) with the learning rate rising exponentially from
+// minLR to maxLR. It returns each step's rate and the loss of its batch
+// before the update, stopping early once the loss is no longer finite or
+// exceeds four times the lowest seen. A good LearningRate sits somewhat
+// below where the loss starts to climb. Both slices are nil if the data or
+// the arguments are invalid.
+func (nn *NeuralNet) FindLR(x, y *mat.Dense, minLR, maxLR float64, steps int) ([]float64, []float64) {
+	if !(minLR > 0) || !(maxLR > minLR) || steps < 2 {
+		return nil, nil
+	}
 
-		dOutput := new(mat.Dense)
-		dOutput.MulElem(networkError, slopeOutputLayer)
-		errorAtHiddenLayer := new(mat.Dense)
-		errorAtHiddenLayer.Mul(dOutput, wOut.T())
+	conf := nn.config
+	conf.NumEpochs, conf.ValidationSplit, conf.CheckpointEvery = 0, 0, 0
+	probe := NewNet(conf)
+	if err := probe.Train(x, y); err != nil {
+		return nil, nil
+	}
+	if probe.scaler != nil {
+		x = probe.scaler.transform(x)
+	}
+	randGen := probe.trainRand()
+	numRows, _ := x.Dims()
 
-		dHiddenLayer := new(mat.Dense)
-		dHiddenLayer.MulElem(errorAtHiddenLayer, slopeHiddenLayer)
+	rates := make([]float64, 0, steps)
+	losses := make([]float64, 0, steps)
+	best := math.Inf(1)
+	var batches [][]int
+	epoch := 0
+	for i := 0; i < steps; i++ {
+		if len(batches) == 0 {
+			batches = probe.sampler().Batches(epoch, numRows, randGen)
+			epoch++
+			if len(batches) == 0 {
+				break
+			}
+		}
+		rows := batches[0]
+		batches = batches[1:]
+		xBatch, yBatch := x, y
+		if rows != nil {
+			xBatch, yBatch = selectRows(x, rows), selectRows(y, rows)
+		}
 
+		lr := minLR * math.Pow(maxLR/minLR, float64(i)/float64(steps-1))
+		output, _, err := probe.step(xBatch, yBatch, probe.wHidden, probe.bHidden, probe.wOut, probe.bOut, lr)
+		if err != nil {
+			break
+		}
+		loss := probe.loss(yBatch, output)
+		rates = append(rates, lr)
+		losses = append(losses, loss)
 
-		wOutAdj := new(mat.Dense)
-		wOutAdj.Mul(hiddenLayerActivations.T(), dOutput)
-		wOutAdj.Scale(nn.config.LearningRate, wOutAdj)
-		wOut.Add(wOut, wOutAdj)
+		if math.IsNaN(loss) || math.IsInf(loss, 0) || loss > 4*best {
+			break
+		}
+		best = math.Min(best, loss)
+	}
 
-		bOutAdj, err := sumAlongAxis(0, dOutput)
-		if err != nil {
-			return err
+	return rates, losses
+}
+
+// ContinueTraining runs another NumEpochs epochs starting from the current
+// weights, e.g. after LoadNet restored a checkpoint. The optimizer state,
+// the learning rate plateaus have reduced to and the position of the
+// training stream carry over, so given the x and y of the original Train
+// call it picks up where a checkpoint left off: the same validation rows are
+// held out and the same batches drawn. The plateau, early-stopping and
+// RestoreBestWeights bookkeeping starts afresh with each call.
+func (nn *NeuralNet) ContinueTraining(x, y *mat.Dense) error {
+	if nn.wHidden == nil || nn.wOut == nil {
+		return fmt.Errorf("%w: the supplied weights are empty", ErrNotTrained)
+	}
+	if err := nn.checkTrainingData(x, y); err != nil {
+		return err
+	}
+
+	nn.best = nil
+
+	x, y, xVal, yVal := nn.splitValidation(x, y)
+	if nn.scaler != nil {
+		x = nn.scaler.transform(x)
+		if xVal != nil {
+			xVal = nn.scaler.transform(xVal)
 		}
-		bOutAdj.Scale(nn.config.LearningRate, bOutAdj)
+	}
+
+	return nn.backpropagate(x, y, xVal, yVal, nn.wHidden, nn.bHidden, nn.wOut, nn.bOut)
+}
+
+// splitValidation holds out a random ValidationSplit fraction of the rows,
+// per class when StratifiedSplit is set. The returned validation matrices
+// are nil when nothing is held out. The split is the first draw of the
+// training stream, so once it is seeded the same rows are held out again
+// without moving the stream on.
+func (nn *NeuralNet) splitValidation(x, y *mat.Dense) (xTrain, yTrain, xVal, yVal *mat.Dense) {
+	randGen := nn.trainRand()
+	if nn.rngSource != nil && nn.rngSource.draws > 0 {
+		randGen = rand.New(rand.NewSource(nn.rngSource.seed))
+	}
+	trainRows, valRows := splitRows(y, nn.config.ValidationSplit, nn.config.StratifiedSplit, randGen)
+	if valRows == nil {
+		return x, y, nil, nil
+	}
 
-		bOut.Add(bOut, bOutAdj)
+	return selectRows(x, trainRows), selectRows(y, trainRows), selectRows(x, valRows), selectRows(y, valRows)
+}
 
-		wHiddenAdj := new(mat.Dense)
-		wHiddenAdj.Mul(x.T(), dHiddenLayer)
-		wHiddenAdj.Scale(nn.config.LearningRate, wHiddenAdj)
+// TrainTestSplit holds out a random testFraction of the rows of x and y as a
+// test set. With stratified, each class of y (the thresholded output, or the
+// argmax of several) is split separately so both halves keep the class
+// distribution. A zero seed seeds from the clock.
+func TrainTestSplit(x, y *mat.Dense, testFraction float64, stratified bool, seed int64) (xTrain, yTrain, xTest, yTest *mat.Dense, err error) {
+	xRows, _ := x.Dims()
+	yRows, _ := y.Dims()
+	if xRows != yRows {
+		return nil, nil, nil, nil, fmt.Errorf("%w: input has %d rows but targets have %d", ErrDimensionMismatch, xRows, yRows)
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
 
-		wHidden.Add(wHidden, wHiddenAdj)
+	trainRows, testRows := splitRows(y, testFraction, stratified, rand.New(rand.NewSource(seed)))
+	if testRows == nil {
+		return x, y, nil, nil, nil
+	}
 
-		bHiddenAdj, err := sumAlongAxis(0, dHiddenLayer)
+	return selectRows(x, trainRows), selectRows(y, trainRows), selectRows(x, testRows), selectRows(y, testRows), nil
+}
+
+// CrossValidate runs k-fold cross-validation: the rows of x and y are dealt
+// into k folds, and for each fold a fresh net built from conf is trained on
+// the other k-1 and scored on it. It returns the held-out loss of each fold.
+// With stratified, every class of y (as in TrainTestSplit) is dealt out
+// separately so each fold keeps the class proportions. A zero seed seeds
+// from the clock.
+func CrossValidate(x, y *mat.Dense, conf NetConfig, k int, stratified bool, seed int64) ([]float64, error) {
+	if err := NewNet(conf).checkTrainingData(x, y); err != nil {
+		return nil, err
+	}
+	numRows, _ := x.Dims()
+	if k < 2 || k > numRows {
+		return nil, fmt.Errorf("k-fold needs between 2 and %d folds, got %d", numRows, k)
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	folds := foldRows(y, k, stratified, rand.New(rand.NewSource(seed)))
+	losses := make([]float64, k)
+	for i, testRows := range folds {
+		var trainRows []int
+		for j, rows := range folds {
+			if j != i {
+				trainRows = append(trainRows, rows...)
+			}
+		}
+		sort.Ints(trainRows)
+
+		nn := NewNet(conf)
+		if err := nn.Train(selectRows(x, trainRows), selectRows(y, trainRows)); err != nil {
+			return nil, fmt.Errorf("fold %d: %w", i, err)
+		}
+		yTest := selectRows(y, testRows)
+		output, err := nn.Predict(selectRows(x, testRows))
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("fold %d: %w", i, err)
+		}
+		losses[i] = nn.loss(yTest, output)
+	}
+
+	return losses, nil
+}
+
+// trainRand returns the stream used for sampling during training. Nets
+// restored from disk pick the stream back up from the configured seed.
+func (nn *NeuralNet) trainRand() *rand.Rand {
+	if nn.rng == nil {
+		seed := nn.config.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		nn.seedTrainRand(seed - 1 + int64(nn.epoch))
+	}
+	return nn.rng
+}
+
+func (nn *NeuralNet) seedTrainRand(seed int64) {
+	nn.rngSource = &countingSource{src: rand.NewSource(seed).(rand.Source64), seed: seed}
+	nn.rng = rand.New(nn.rngSource)
+}
+
+// countingSource is a rand.Source64 that counts its draws, so the position
+// of a seeded stream can be saved as the seed and a count and replayed.
+type countingSource struct {
+	src   rand.Source64
+	seed  int64
+	draws uint64
+}
+
+func (c *countingSource) Int63() int64 {
+	c.draws++
+	return c.src.Int63()
+}
+
+func (c *countingSource) Uint64() uint64 {
+	c.draws++
+	return c.src.Uint64()
+}
+
+func (c *countingSource) Seed(seed int64) {
+	c.src.Seed(seed)
+	c.seed, c.draws = seed, 0
+}
+
+// skip advances the stream by n draws.
+func (c *countingSource) skip(n uint64) {
+	for ; c.draws < n; c.draws++ {
+		c.src.Int63()
+	}
+}
+
+// Epoch reports how many epochs the current weights have been trained for.
+func (nn *NeuralNet) Epoch() int {
+	return nn.epoch
+}
+
+func (nn *NeuralNet) checkTrainingData(x, y *mat.Dense) error {
+	if x == nil || y == nil || x.IsEmpty() || y.IsEmpty() {
+		return fmt.Errorf("%w: no training rows", ErrEmptyData)
+	}
+	xRows, xCols := x.Dims()
+	yRows, yCols := y.Dims()
+	if xCols != nn.config.InputNeurons {
+		return fmt.Errorf("%w: input has %d columns but InputNeurons is %d", ErrDimensionMismatch, xCols, nn.config.InputNeurons)
+	}
+	if yCols != nn.config.OutputNeurons {
+		return fmt.Errorf("%w: targets have %d columns but OutputNeurons is %d", ErrDimensionMismatch, yCols, nn.config.OutputNeurons)
+	}
+	if xRows != yRows {
+		return fmt.Errorf("%w: input has %d rows but targets have %d", ErrDimensionMismatch, xRows, yRows)
+	}
+	if m := nn.config.LayerLRMultipliers; m != nil && len(m) != 2 {
+		return fmt.Errorf("%w: got %d learning-rate multipliers for 2 layers", ErrDimensionMismatch, len(m))
+	}
+	if n := len(nn.config.HiddenActivations); n > 1 {
+		return fmt.Errorf("%w: got %d hidden activations for 1 hidden layer", ErrDimensionMismatch, n)
+	}
+	if nn.config.OutputActivation == PReLU {
+		return fmt.Errorf("PReLU learns per hidden unit and is not available as the output activation")
+	}
+	for _, row := range nn.config.FrozenInputs {
+		if row < 0 || row >= xCols {
+			return fmt.Errorf("%w: frozen input column %d of %d", ErrDimensionMismatch, row, xCols)
+		}
+	}
+	if w := nn.config.OutputLossWeights; w != nil && len(w) != yCols {
+		return fmt.Errorf("%w: got %d output loss weights for %d outputs", ErrDimensionMismatch, len(w), yCols)
+	}
+	for _, tau := range nn.config.OutputQuantiles {
+		if tau < 0 || tau >= 1 {
+			return fmt.Errorf("output quantile %v is outside [0, 1)", tau)
+		}
+	}
+	if q := nn.config.OutputQuantiles; q != nil && len(q) != yCols {
+		return fmt.Errorf("%w: got %d output quantiles for %d outputs", ErrDimensionMismatch, len(q), yCols)
+	}
+	if nn.config.LossEpsilon < 0 {
+		return fmt.Errorf("loss epsilon must not be negative, got %v", nn.config.LossEpsilon)
+	}
+	if p := nn.config.InputDropout; p < 0 || p >= 1 {
+		return fmt.Errorf("input dropout %v is outside [0, 1)", p)
+	}
+	if nn.config.WarmRestartEpochs < 0 {
+		return fmt.Errorf("warm restart period must not be negative, got %d", nn.config.WarmRestartEpochs)
+	}
+	if m := nn.config.RestartMultiplier; m != 0 && m < 1 {
+		return fmt.Errorf("restart multiplier must be at least 1, got %v", m)
+	}
+	if nn.config.AdaptiveBatchMax < 0 {
+		return fmt.Errorf("adaptive batch cap must not be negative, got %d", nn.config.AdaptiveBatchMax)
+	}
+	if nn.config.AdaptiveBatchMax > 0 && (nn.config.BatchSize <= 0 || nn.config.Sampler != nil) {
+		return fmt.Errorf("adaptive batch sizing needs a BatchSize and the default Sampler")
+	}
+	if nn.config.CheckpointEvery > 0 && nn.config.CheckpointPath == "" {
+		return fmt.Errorf("checkpointing every %d epochs needs a CheckpointPath", nn.config.CheckpointEvery)
+	}
+	if nn.config.CrossEntropy {
+		if nn.config.OutputActivation != None {
+			return fmt.Errorf("cross-entropy training needs OutputActivation None, the outputs are logits")
+		}
+		if nn.config.OutputLossWeights != nil || nn.config.OutputQuantiles != nil || nn.config.LossEpsilon != 0 {
+			return fmt.Errorf("cross-entropy training does not combine with output loss weights, quantiles or a loss epsilon")
 		}
-		bHiddenAdj.Scale(nn.config.LearningRate, bHiddenAdj)
-		bHidden.Add(bHidden, bHiddenAdj)
 	}
 	return nil
 }
 
-func (nn *NeuralNet) Predict(x *mat.Dense) (*mat.Dense, error) {
+func initLayer(seed int64, inputs, outputs int) (*mat.Dense, *mat.Dense) {
+	randGen := rand.New(rand.NewSource(seed))
+
+	w := mat.NewDense(inputs, outputs, nil)
+	b := mat.NewDense(1, outputs, nil)
+
+	for _, param := range [][]float64{
+		w.RawMatrix().Data,
+		b.RawMatrix().Data,
+	} {
+		for i := range param {
+			param[i] = randGen.Float64()
+		}
+	}
+
+	return w, b
+}
+
+func (nn *NeuralNet) backpropagate(x, y, xVal, yVal, wHidden, bHidden, wOut, bOut *mat.Dense) error {
+	randGen := nn.trainRand()
+
+	lr := nn.config.LearningRate
+	if nn.lr > 0 {
+		lr = nn.lr
+	}
+	plateauFactor := nn.config.PlateauFactor
+	if plateauFactor == 0 {
+		plateauFactor = 0.1
+	}
+	bestLoss, sinceBest, sinceReduce := math.Inf(1), 0, 0
+
+	accSmoothing := nn.config.AccuracySmoothing
+	if accSmoothing == 0 {
+		accSmoothing = 0.9
+	}
+	var accEMA float64
+	var accSteps int
+
+	progressInterval := nn.config.ProgressInterval
+	if progressInterval == 0 {
+		progressInterval = time.Second
+	}
+	start := time.Now()
+	lastProgress := start
+	lastEpoch := nn.epoch + nn.config.NumEpochs
+
+	params := []namedParam{
+		{"wHidden", wHidden},
+		{"bHidden", bHidden},
+		{"wOut", wOut},
+		{"bOut", bOut},
+	}
+	if nn.slopes != nil {
+		params = append(params, namedParam{"slopes", nn.slopes})
+	}
+	// before holds each parameter ahead of a step, then the step's update
+	before := make([]*mat.Dense, len(params))
+	for i, p := range params {
+		r, c := p.m.Dims()
+		before[i] = mat.NewDense(r, c, nil)
+	}
+
+	for i := 0; i < nn.config.NumEpochs; i++ {
+		xEpoch, yEpoch := x, y
+		var epochRows []int
+		if nn.config.Undersample {
+			epochRows = balancedRows(y, randGen)
+			xEpoch, yEpoch = selectRows(x, epochRows), selectRows(y, epochRows)
+		}
+		if nn.config.Augment != nil {
+			// the hook works on a copy so x is the same every epoch
+			xEpoch = nn.config.Augment(mat.DenseCopyOf(xEpoch), nn.epoch)
+		}
+
+		epochLR := lr
+		if nn.config.WarmRestartEpochs > 0 {
+			epochLR *= warmRestartFactor(nn.epoch, nn.config.WarmRestartEpochs, orDefault(nn.config.RestartMultiplier, 1))
+		}
+		epochBatchSize := nn.currentBatchSize()
+		if nn.batchSize > nn.config.BatchSize && nn.config.Optimizer.Kind != Adam {
+			// gradients are summed over a batch, so a grown batch takes
+			// steps of the same size as one of BatchSize rows, only less
+			// noisy; Adam normalizes the gradient's scale itself
+			epochLR *= float64(nn.config.BatchSize) / float64(nn.batchSize)
+		}
+
+		numRows, _ := xEpoch.Dims()
+		var lossSum, accSum float64
+		trainedRows := 0
+		ratios := make([]float64, len(params))
+		ratioSteps := make([]int, len(params))
+		var noise gradientNoise
+		for b, rows := range nn.sampler().Batches(nn.epoch, numRows, randGen) {
+			xBatch, yBatch := xEpoch, yEpoch
+			if rows != nil {
+				xBatch, yBatch = selectRows(xEpoch, rows), selectRows(yEpoch, rows)
+			}
+			batchSize, _ := xBatch.Dims()
+
+			if nn.config.OnBatch != nil {
+				nn.config.OnBatch(nn.epoch, b, sourceRows(rows, epochRows, batchSize))
+			}
+			if p := nn.config.InputDropout; p > 0 {
+				xBatch = dropColumns(mat.DenseCopyOf(xBatch), p, randGen)
+			}
+
+			for i, p := range params {
+				before[i].Copy(p.m)
+			}
+
+			output, grads, err := nn.step(xBatch, yBatch, wHidden, bHidden, wOut, bOut, epochLR)
+			if err != nil {
+				return err
+			}
+			noise.add(grads, batchSize)
+
+			for i, p := range params {
+				// an all-zero matrix, such as slopes clamped to 0, has no
+				// scale to compare the update with
+				norm := mat.Norm(before[i], 2)
+				if norm == 0 {
+					continue
+				}
+				before[i].Sub(p.m, before[i])
+				ratios[i] += mat.Norm(before[i], 2) / norm
+				ratioSteps[i]++
+			}
+			trainedRows += batchSize
+
+			lossSum += nn.loss(yBatch, output) * float64(batchSize)
+
+			acc := accuracy(yBatch, output)
+			accSum += acc * float64(batchSize)
+			accSteps++
+			accEMA = accSmoothing*accEMA + (1-accSmoothing)*acc
+		}
+		// a Sampler may repeat or skip rows, so average over those trained on
+		trainLoss := lossSum / float64(trainedRows)
+		if limit := nn.config.AdaptiveBatchMax; limit > epochBatchSize {
+			// one epoch's estimate is noisy, so grow by at most double
+			limit = min(limit, 2*epochBatchSize)
+			if b := noise.scale(); b >= float64(limit) {
+				nn.batchSize = limit
+			} else if b > float64(epochBatchSize) {
+				nn.batchSize = int(math.Ceil(b))
+			}
+		}
+
+		nn.epoch++
+
+		epochReport := EpochReport{
+			Epoch:        nn.epoch,
+			TrainLoss:    trainLoss,
+			ValLoss:      math.NaN(),
+			LearningRate: epochLR,
+			BatchSize:    epochBatchSize,
+			Accuracy:     accSum / float64(trainedRows),
+			// bias-corrected so early epochs aren't pulled towards zero
+			SmoothedAccuracy: accEMA / (1 - math.Pow(accSmoothing, float64(accSteps))),
+			Weights:          summarizeWeights(params),
+			UpdateRatios:     make(map[string]float64, len(params)),
+		}
+		for i, p := range params {
+			epochReport.UpdateRatios[p.name] = ratios[i] / float64(max(ratioSteps[i], 1))
+		}
+		monitored := trainLoss
+		if xVal != nil {
+			epochReport.ValLoss = nn.loss(yVal, nn.forward(xVal))
+			monitored = epochReport.ValLoss
+		}
+		nn.report.Epochs = append(nn.report.Epochs, epochReport)
+		if nn.best == nil || monitored < nn.best.loss {
+			nn.best = &bestWeights{epoch: nn.epoch, loss: monitored, params: copyParams(params)}
+			nn.report.BestEpoch = nn.epoch
+		}
+
+		// changes smaller than MinDelta are noise, not progress
+		if monitored < bestLoss-nn.config.MinDelta {
+			bestLoss, sinceBest, sinceReduce = monitored, 0, 0
+		} else {
+			sinceBest++
+			sinceReduce++
+			if nn.config.PlateauPatience > 0 && sinceReduce >= nn.config.PlateauPatience {
+				lr *= plateauFactor
+				nn.lr = lr
+				sinceReduce = 0
+			}
+		}
+
+		if nn.config.CheckpointEvery > 0 && nn.epoch%nn.config.CheckpointEvery == 0 {
+			if err := nn.SaveFile(nn.config.CheckpointPath); err != nil {
+				return fmt.Errorf("checkpoint at epoch %d: %w", nn.epoch, err)
+			}
+		}
+
+		stop := nn.config.EarlyStoppingPatience > 0 && sinceBest >= nn.config.EarlyStoppingPatience
+		if w := nn.config.ProgressWriter; w != nil {
+			now := time.Now()
+			if stop || i == nn.config.NumEpochs-1 || now.Sub(lastProgress) >= progressInterval {
+				elapsed := now.Sub(start)
+				eta := elapsed / time.Duration(i+1) * time.Duration(nn.config.NumEpochs-i-1)
+				if stop {
+					eta = 0
+				}
+				writeProgress(w, epochReport, lastEpoch, eta)
+				lastProgress = now
+			}
+		}
+
+		if stop {
+			nn.report.StoppedEarly = true
+			break
+		}
+	}
+
+	if nn.config.RestoreBestWeights && nn.best != nil {
+		for i, p := range params {
+			p.m.Copy(nn.best.params[i])
+		}
+		nn.syncOutWeights()
+	}
+	return nil
+}
+
+// warmRestartFactor is SGDR's scale on the learning rate at epoch, counted
+// from 0: 1 at the start of each cycle, falling along a half cosine towards
+// 0 at its end. Cycles start period epochs long and grow by mult each time.
+func warmRestartFactor(epoch, period int, mult float64) float64 {
+	t, length := float64(epoch), float64(period)
+	for t >= length {
+		t -= length
+		length *= mult
+	}
+	return 0.5 * (1 + math.Cos(math.Pi*t/length))
+}
+
+// step runs one forward and backward pass over a batch and applies the
+// update in place, returning the batch's pre-update output and the
+// gradients it stepped along, in step order.
+func (nn *NeuralNet) step(x, y, wHidden, bHidden, wOut, bOut *mat.Dense, lr float64) (*mat.Dense, []*mat.Dense, error) {
+	params := []*mat.Dense{wHidden, bHidden, wOut, bOut}
+	if nn.slopes != nil {
+		params = append(params, nn.slopes)
+	}
+	at := nn.evalPoint(params)
+	var slopes *mat.Dense
+	if nn.slopes != nil {
+		slopes = at[4]
+	}
+
+	output, gWHidden, gBHidden, gWOut, gBOut, gSlopes, err := nn.gradients(x, y, at[0], at[1], at[2], at[3], slopes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, row := range nn.config.FrozenInputs {
+		zeroRow(gWHidden, row)
+		if nn.velocity != nil {
+			zeroRow(nn.velocity[0], row)
+		}
+	}
+	if nn.epoch < nn.config.OutputOnlyEpochs {
+		gWHidden.Zero()
+		gBHidden.Zero()
+		if gSlopes != nil {
+			gSlopes.Zero()
+		}
+		if nn.velocity != nil {
+			nn.velocity[0].Zero()
+			nn.velocity[1].Zero()
+			if gSlopes != nil {
+				nn.velocity[4].Zero()
+			}
+		}
+	}
+
+	hiddenLR, outLR := lr*nn.layerLRMultiplier(0), lr*nn.layerLRMultiplier(1)
+	grads := []*mat.Dense{gWHidden, gBHidden, gWOut, gBOut}
+	lrs := []float64{hiddenLR, hiddenLR, outLR, outLR}
+	if gSlopes != nil {
+		grads, lrs = append(grads, gSlopes), append(lrs, hiddenLR)
+	}
+	nn.update(params, grads, lrs)
+	nn.syncOutWeights()
+
+	// hiddenPrime reads the slope off the activation's sign, which a
+	// negative slope would flip
+	if nn.slopes != nil {
+		nn.slopes.Apply(func(_, _ int, v float64) float64 { return math.Max(0, v) }, nn.slopes)
+	}
+
+	return output, grads, nil
+}
+
+// Gradients runs one forward and backward pass over x and y and returns the
+// gradients of the training loss with respect to each parameter, without
+// changing the net. The loss is half the summed squared error (pinball for
+// quantile outputs) over the batch, weighted by OutputLossWeights, so a
+// gradient-descent step at rate lr subtracts lr times each gradient. Inputs
+// are normalized with the fitted scaler, as in Predict.
+func (nn *NeuralNet) Gradients(x, y *mat.Dense) (gWHidden, gBHidden, gWOut, gBOut *mat.Dense, err error) {
 	if nn.wHidden == nil || nn.wOut == nil {
-		return nil, fmt.Errorf("the supplied weights are empty")
+		return nil, nil, nil, nil, fmt.Errorf("%w: the supplied weights are empty", ErrNotTrained)
 	}
-	if nn.bHidden == nil || nn.bOut == nil {
-		return nil, fmt.Errorf("the supplied biases are empty")
+	if err := nn.checkTrainingData(x, y); err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if nn.scaler != nil {
+		x = nn.scaler.transform(x)
 	}
 
-	output := new(mat.Dense)
+	_, gWHidden, gBHidden, gWOut, gBOut, _, err = nn.gradients(x, y, nn.wHidden, nn.bHidden, nn.wOut, nn.bOut, nn.slopes)
+	return gWHidden, gBHidden, gWOut, gBOut, err
+}
 
+// gradients is the forward and backward pass behind step and Gradients. It
+// returns the batch output alongside the parameter gradients; slopes and
+// gSlopes are nil unless the hidden layer is PReLU.
+func (nn *NeuralNet) gradients(x, y, wHidden, bHidden, wOut, bOut, slopes *mat.Dense) (output, gWHidden, gBHidden, gWOut, gBOut, gSlopes *mat.Dense, err error) {
 	hiddenLayerInput := new(mat.Dense)
-	hiddenLayerInput.Mul(x, nn.wHidden)
-	addBHidden := func(_, col int, v float64) float64 { return v + nn.bHidden.At(0, col) }
+	nn.mul(hiddenLayerInput, x, wHidden)
+	addBHidden := func(_, col int, v float64) float64 { return v + bHidden.At(0, col) }
 	hiddenLayerInput.Apply(addBHidden, hiddenLayerInput)
 
 	hiddenLayerActivations := new(mat.Dense)
-	applySigmoid := func(_, _ int, v float64) float64 { return sigmoid(v) }
-	hiddenLayerActivations.Apply(applySigmoid, hiddenLayerInput)
+	applyHiddenActivation := func(_, col int, v float64) float64 { return nn.hiddenApply(slopes, col, v) }
+	hiddenLayerActivations.Apply(applyHiddenActivation, hiddenLayerInput)
 
 	outputLayerInput := new(mat.Dense)
-	outputLayerInput.Mul(hiddenLayerActivations, nn.wOut)
+	nn.mul(outputLayerInput, hiddenLayerActivations, nn.outWeights(wOut))
+	addBOut := func(_, col int, v float64) float64 { return v + bOut.At(0, col) }
+	outputLayerInput.Apply(addBOut, outputLayerInput)
+	output = new(mat.Dense)
+	applyOutputActivation := func(_, _ int, v float64) float64 { return nn.config.OutputActivation.apply(v) }
+	output.Apply(applyOutputActivation, outputLayerInput)
+
+
+	networkError := nn.outputError(y, output)
+
+	slopeOutputLayer := new(mat.Dense)
+	applyOutputPrime := func(_, _ int, v float64) float64 { return nn.config.OutputActivation.prime(v) }
+	slopeOutputLayer.Apply(applyOutputPrime, output)
+
+	slopeHiddenLayer := new(mat.Dense)
+	applyHiddenPrime := func(_, col int, v float64) float64 { return nn.hiddenPrime(slopes, col, v) }
+	slopeHiddenLayer.Apply(applyHiddenPrime, hiddenLayerActivations)
+
+
+	dOutput := new(mat.Dense)
+	dOutput.MulElem(networkError, slopeOutputLayer)
+	errorAtHiddenLayer := new(mat.Dense)
+	nn.mul(errorAtHiddenLayer, dOutput, wOut.T())
+
+	dHiddenLayer := new(mat.Dense)
+	dHiddenLayer.MulElem(errorAtHiddenLayer, slopeHiddenLayer)
+
+	// dOutput and dHiddenLayer point down the loss, so the gradients are
+	// their negations
+	gWOut = new(mat.Dense)
+	nn.mul(gWOut, hiddenLayerActivations.T(), dOutput)
+	gWOut.Scale(-1, gWOut)
+
+	gBOut, err = sumAlongAxis(0, dOutput)
+	if err != nil {
+		return nil, nil, nil, nil, nil, nil, err
+	}
+	gBOut.Scale(-1, gBOut)
+
+	gWHidden = new(mat.Dense)
+	nn.mul(gWHidden, x.T(), dHiddenLayer)
+	gWHidden.Scale(-1, gWHidden)
+
+	gBHidden, err = sumAlongAxis(0, dHiddenLayer)
+	if err != nil {
+		return nil, nil, nil, nil, nil, nil, err
+	}
+	gBHidden.Scale(-1, gBHidden)
+
+	// a unit's slope scales its negative pre-activations and nothing else
+	if slopes != nil {
+		gSlopes = mat.NewDense(1, nn.config.HiddenNeurons, nil)
+		numRows, _ := hiddenLayerInput.Dims()
+		for i := 0; i < numRows; i++ {
+			for j, z := range hiddenLayerInput.RawRowView(i) {
+				if z < 0 {
+					gSlopes.Set(0, j, gSlopes.At(0, j)-errorAtHiddenLayer.At(i, j)*z)
+				}
+			}
+		}
+	}
+
+	return output, gWHidden, gBHidden, gWOut, gBOut, gSlopes, nil
+}
+
+// layerLRMultiplier scales the learning rate of layer 0 (hidden) or 1
+// (output).
+func (nn *NeuralNet) layerLRMultiplier(layer int) float64 {
+	if nn.config.LayerLRMultipliers == nil {
+		return 1
+	}
+	return nn.config.LayerLRMultipliers[layer]
+}
+
+// hiddenActivation is the activation of the (single) hidden layer. A lone
+// entry in HiddenActivations applies to every hidden layer.
+func (nn *NeuralNet) hiddenActivation() Activation {
+	if len(nn.config.HiddenActivations) == 0 {
+		return Sigmoid
+	}
+	return nn.config.HiddenActivations[0]
+}
+
+// hiddenApply is the hidden activation of pre-activation v in column col,
+// using slopes for PReLU.
+func (nn *NeuralNet) hiddenApply(slopes *mat.Dense, col int, v float64) float64 {
+	if nn.hiddenActivation() == PReLU && v <= 0 {
+		return slopes.At(0, col) * v
+	}
+	return nn.hiddenActivation().apply(v)
+}
+
+// hiddenPrime is Activation.prime for the hidden layer, using slopes for
+// PReLU; like prime it takes the activation, not the pre-activation.
+func (nn *NeuralNet) hiddenPrime(slopes *mat.Dense, col int, v float64) float64 {
+	if nn.hiddenActivation() == PReLU && v <= 0 {
+		return slopes.At(0, col)
+	}
+	return nn.hiddenActivation().prime(v)
+}
+
+// transposedWeights is wOut kept in transposed layout, for TransposedWOut.
+type transposedWeights struct {
+	of *mat.Dense // the wOut it mirrors
+	t  *mat.Dense
+}
+
+// outWeights is wOut as the output-layer product should read it. With
+// TransposedWOut it is the transpose of the net's transposed mirror of
+// wOut, which gonum multiplies faster when there are few outputs. The
+// mirror is built the first time it is needed and kept in step by step;
+// any other wOut, such as a Nesterov look-ahead, gets a transposed copy.
+func (nn *NeuralNet) outWeights(wOut *mat.Dense) mat.Matrix {
+	if !nn.config.TransposedWOut {
+		return wOut
+	}
+	if c := nn.wOutT.Load(); c != nil && c.of == wOut {
+		return c.t.T()
+	}
+
+	t := mat.DenseCopyOf(wOut.T())
+	if wOut == nn.wOut {
+		nn.wOutT.Store(&transposedWeights{of: wOut, t: t})
+	}
+	return t.T()
+}
+
+// syncOutWeights rewrites the transposed mirror of wOut in place after
+// wOut itself was changed in place.
+func (nn *NeuralNet) syncOutWeights() {
+	if c := nn.wOutT.Load(); c != nil && c.of == nn.wOut {
+		c.t.Copy(nn.wOut.T())
+	}
+}
 
+func (nn *NeuralNet) mul(dst *mat.Dense, a, b mat.Matrix) {
+	if nn.config.MixedPrecision {
+		mulFloat32(dst, a, b)
+		return
+	}
+	matMul(dst, a, b)
+}
+
+// matMul is dst.Mul(a, b), run on the GPU for large products when built
+// with the cuda tag.
+func matMul(dst *mat.Dense, a, b mat.Matrix) {
+	if !deviceMul(dst, a, b) {
+		dst.Mul(a, b)
+	}
+}
+
+// Predict runs x through the net. It only reads the net and allocates its
+// intermediates per call, so any number of goroutines may call it (and the
+// other Predict methods) on one net at once, provided none is training or
+// loading weights into it at the same time.
+func (nn *NeuralNet) Predict(x *mat.Dense) (*mat.Dense, error) {
+	if nn.wHidden == nil || nn.wOut == nil {
+		return nil, fmt.Errorf("%w: the supplied weights are empty", ErrNotTrained)
+	}
+	if nn.bHidden == nil || nn.bOut == nil {
+		return nil, fmt.Errorf("%w: the supplied biases are empty", ErrNotTrained)
+	}
+
+	if nn.scaler != nil {
+		x = nn.scaler.transform(x)
+	}
+
+	return nn.forward(x), nil
+}
+
+// forward runs already-normalized inputs through the current weights.
+func (nn *NeuralNet) forward(x mat.Matrix) *mat.Dense {
+	output := new(mat.Dense)
+	nn.forwardInto(output, new(mat.Dense), x)
+	return output
+}
+
+// forwardInto is forward writing into caller-owned matrices; hidden holds
+// the hidden activations. Both may be empty or already sized for x.
+func (nn *NeuralNet) forwardInto(output, hidden *mat.Dense, x mat.Matrix) {
+	nn.logitsInto(output, hidden, x)
+	applyOutputActivation := func(_, _ int, v float64) float64 { return nn.config.OutputActivation.apply(v) }
+	output.Apply(applyOutputActivation, output)
+}
+
+// logitsInto is forwardInto stopping short of the output activation.
+func (nn *NeuralNet) logitsInto(logits, hidden *mat.Dense, x mat.Matrix) {
+	matMul(hidden, x, nn.wHidden)
+	applyHiddenActivation := func(_, col int, v float64) float64 {
+		return nn.hiddenApply(nn.slopes, col, v+nn.bHidden.At(0, col))
+	}
+	hidden.Apply(applyHiddenActivation, hidden)
+
+	matMul(logits, hidden, nn.outWeights(nn.wOut))
 	addBOut := func(_, col int, v float64) float64 { return v + nn.bOut.At(0, col) }
-	outputLayerInput.Apply(addBOut, outputLayerInput)
-	output.Apply(applySigmoid, outputLayerInput)
+	logits.Apply(addBOut, logits)
+}
+
+// PredictProba is Predict with temperature scaling: the output layer's
+// pre-activations are divided by temperature before the output activation.
+// Above 1 it softens probabilities towards uniform, below 1 it sharpens
+// them, and 1 matches Predict.
+func (nn *NeuralNet) PredictProba(x *mat.Dense, temperature float64) (*mat.Dense, error) {
+	if nn.wHidden == nil || nn.wOut == nil {
+		return nil, fmt.Errorf("%w: the supplied weights are empty", ErrNotTrained)
+	}
+	if nn.bHidden == nil || nn.bOut == nil {
+		return nil, fmt.Errorf("%w: the supplied biases are empty", ErrNotTrained)
+	}
+	if !(temperature > 0) {
+		return nil, fmt.Errorf("temperature must be positive, got %v", temperature)
+	}
+
+	var in mat.Matrix = x
+	if nn.scaler != nil {
+		in = nn.scaler.transform(x)
+	}
+
+	output := new(mat.Dense)
+	nn.logitsInto(output, new(mat.Dense), in)
+	applyOutputActivation := func(_, _ int, v float64) float64 {
+		return nn.config.OutputActivation.apply(v / temperature)
+	}
+	output.Apply(applyOutputActivation, output)
 
 	return output, nil
 }
 
+// NumParameters counts the trainable weights and biases; it is zero until
+// the net has been trained or loaded.
+func (nn *NeuralNet) NumParameters() int {
+	count := 0
+	for _, m := range []*mat.Dense{nn.wHidden, nn.bHidden, nn.wOut, nn.bOut, nn.slopes} {
+		if m != nil {
+			r, c := m.Dims()
+			count += r * c
+		}
+	}
+	return count
+}
+
+func (nn *NeuralNet) TrainingReport() TrainingReport {
+	return nn.report
+}
+
+// bestWeights holds a copy of the parameters, in step order, at the epoch
+// with the lowest monitored loss of the current training run.
+type bestWeights struct {
+	epoch  int
+	loss   float64
+	params []*mat.Dense
+}
+
+// BestWeights returns a copy of the net with the weights of the epoch with
+// the lowest validation loss, or training loss without a split, since the
+// last Train or ContinueTraining call (TrainingReport.BestEpoch). It is nil
+// until an epoch has been trained, and is not saved with the net.
+func (nn *NeuralNet) BestWeights() *NeuralNet {
+	if nn.best == nil {
+		return nil
+	}
+
+	best := NewNet(nn.config)
+	best.scaler = nn.scaler
+	best.epoch = nn.best.epoch
+	params := make([]*mat.Dense, len(nn.best.params))
+	for i, p := range nn.best.params {
+		params[i] = mat.DenseCopyOf(p)
+	}
+	best.wHidden, best.bHidden, best.wOut, best.bOut = params[0], params[1], params[2], params[3]
+	if len(params) > 4 {
+		best.slopes = params[4]
+	}
+	return best
+}
+
+func (nn *NeuralNet) PredictDecoded(ni *NeuralInterface, input map[string]interface{}) (map[string]float64, error) {
+	x, err := ni.EncodeInput(input)
+	if err != nil {
+		return nil, err
+	}
+	output, err := nn.Predict(x)
+	if err != nil {
+		return nil, err
+	}
+	return nn.decode(ni, output)
+}
+
+// decode is ni.Decode for an output row of the net. A CrossEntropy net
+// outputs logits, so the row is first replaced by its softmax and
+// Probability outputs decode as probabilities rather than clamped logits.
+func (nn *NeuralNet) decode(ni *NeuralInterface, output *mat.Dense) (map[string]float64, error) {
+	if nn.config.CrossEntropy {
+		output = mat.NewDense(1, len(output.RawRowView(0)), softmax(output.RawRowView(0)))
+	}
+	return ni.Decode(output)
+}
+
+// Classify predicts input and picks the most likely of the Probability
+// outputs. probs normalizes them into one distribution over their names,
+// the decoded probabilities divided by their sum; with CrossEntropy that is
+// the softmax of their logits. confidence is the winner's share, and the
+// label is empty when it falls below ni.AbstainBelow.
+func (nn *NeuralNet) Classify(ni *NeuralInterface, input map[string]interface{}) (label string, confidence float64, probs map[string]float64, err error) {
+	x, err := ni.EncodeInput(input)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	output, err := nn.Predict(x)
+	if err != nil {
+		return "", 0, nil, err
+	}
+	decoded, err := nn.decode(ni, output)
+	if err != nil {
+		return "", 0, nil, err
+	}
+
+	var names []string
+	var scores []float64
+	for _, def := range ni.OutputSchema {
+		if def.Type == Probability {
+			names = append(names, def.Name)
+			scores = append(scores, decoded[def.Name])
+		}
+	}
+	if len(names) == 0 {
+		return "", 0, nil, fmt.Errorf("the output schema has no Probability outputs to classify over")
+	}
+
+	if sum := floats.Sum(scores); sum > 0 {
+		floats.Scale(1/sum, scores)
+	} else {
+		for i := range scores {
+			scores[i] = 1 / float64(len(scores))
+		}
+	}
+	probs = make(map[string]float64, len(names))
+	best := floats.MaxIdx(scores)
+	for i, name := range names {
+		probs[name] = scores[i]
+	}
+
+	if scores[best] < ni.AbstainBelow {
+		return "", scores[best], probs, nil
+	}
+
+	return names[best], scores[best], probs, nil
+}
+
 type FeatureType int
 
 const (
@@ -173,28 +1340,94 @@ const (
 	Continuous                      // float64, normalized
 	Categorical                     // one-hot encoded
 	Probability
+	Cyclic  // float64 with a Period, encoded as sin and cos
+	Ordinal // string from ordered Categories, encoded as its position in [0, 1]
 )
 
 type FeatureDefinition struct {
 	Name       string
 	Type       FeatureType
-	Min        float64      // for Continuous
-	Max        float64      // for Continuous
-	Categories []string     // for Categorical
+	Min        float64  // for Continuous
+	Max        float64  // for Continuous
+	Categories []string // for Categorical, and lowest first for Ordinal
+	Period     float64  // for Cyclic, e.g. 24 for hour of day
+	Mean       *float64 // imputed for a missing Continuous value; nil uses the midpoint of Min and Max
+	// Reference is the Categorical level encoded as all zeros, with no
+	// column of its own (dummy encoding); empty keeps one column per level
+	Reference string
+	// Embedding, if set, encodes a Categorical feature as a fixed vector
+	// per category instead of one-hot; all vectors share one length. A
+	// missing or unlisted category encodes as zeros
+	Embedding map[string][]float64
+}
+
+// dummyCategories lists the Categories that get an input column, leaving
+// out the Reference level.
+func (def FeatureDefinition) dummyCategories() []string {
+	if def.Reference == "" {
+		return def.Categories
+	}
+	categories := make([]string, 0, len(def.Categories))
+	for _, cat := range def.Categories {
+		if cat != def.Reference {
+			categories = append(categories, cat)
+		}
+	}
+	return categories
 }
+
+// scale maps a Continuous value from [Min, Max] onto [0, 1]. A constant
+// feature, with Min equal to Max, carries no information and encodes as the
+// midpoint.
+func (def FeatureDefinition) scale(v float64) float64 {
+	if def.Max == def.Min {
+		return 0.5
+	}
+	return (v - def.Min) / (def.Max - def.Min)
+}
+
+// embeddingWidth is the length of the Embedding vectors, taken from the
+// first category in sorted order so that it does not depend on map order.
+func (def FeatureDefinition) embeddingWidth() int {
+	if len(def.Embedding) == 0 {
+		return 0
+	}
+	return len(def.Embedding[slices.Min(slices.Collect(maps.Keys(def.Embedding)))])
+}
+
 type OutputDefinition struct {
-	Name string
-	Type FeatureType
-	Min  float64
-	Max  float64
+	Name     string
+	Type     FeatureType
+	Min      float64
+	Max      float64
+	Quantile float64 // train as this quantile with the pinball loss, e.g. 0.1/0.5/0.9 for an interval
+	// Categories of a Categorical output, one output column each; its
+	// target is the index of the true category
+	Categories []string
+	Threshold  float64 // Decode maps a Binary output at or above this to 1, else 0; 0 means 0.5
+	// ScaleLike names a Continuous input whose Min and Max scale this
+	// output instead of its own, e.g. for reconstruction targets
+	ScaleLike string
+	// RoundStep makes Decode snap a Continuous output, after rescaling, to
+	// the nearest multiple of it, e.g. 1 for whole dollars; 0 leaves it as is
+	RoundStep float64
 }
 
 type NeuralInterface struct {
 	InputSchema  []FeatureDefinition
 	OutputSchema []OutputDefinition
+	// AbstainBelow makes Classify return an empty label when its confidence,
+	// the winner's share of the normalized Probability outputs, is lower,
+	// so the case can be routed elsewhere. Confidence is at least 1/n over
+	// n classes, so e.g. 0.9 with two classes abstains unless the winner is
+	// nine times as likely as the other
+	AbstainBelow float64
+	// Decode clamps Probability outputs into [0, 1]; StrictProbabilities
+	// makes it return an error for such values instead
+	StrictProbabilities bool
 }
 
-func (ni *NeuralInterface) EncodeInput(input map[string]interface{}) *mat.Dense {
+func (ni *NeuralInterface) EncodeInput(input map[string]interface{}) (*mat.Dense, error) {
 	features := make([]float64, 0)
 
 	for _, def := range ni.InputSchema {
@@ -205,73 +1438,295 @@ func (ni *NeuralInterface) EncodeInput(input map[string]interface{}) *mat.Dense
 			case Binary:
 				features = append(features, 0.0) 
 			case Continuous:
-				features = append(features, (def.Min + def.Max) / 2)
+				if def.Mean != nil {
+					features = append(features, def.scale(*def.Mean))
+				} else {
+					features = append(features, 0.5)
+				}
 			case Categorical:
-				for i := range def.Categories {
-					if i == 0 {
+				if def.Embedding != nil {
+					features = append(features, make([]float64, def.embeddingWidth())...)
+					break
+				}
+				// the first level stands in, or the reference when there is one
+				for i := range def.dummyCategories() {
+					if i == 0 && def.Reference == "" {
 						features = append(features, 1.0)
 					} else {
 						features = append(features, 0.0)
 					}
 				}
+			case Cyclic:
+				features = append(features, 0.0, 0.0)
+			case Ordinal:
+				features = append(features, 0.5)
 			}
 			continue
 		}
 
 		switch def.Type {
 		case Binary:
-			if value.(bool) {
+			flag, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("%w: feature %q expects bool, got %T", ErrBadFeatureType, def.Name, value)
+			}
+			if flag {
 				features = append(features, 1.0)
 			} else {
 				features = append(features, 0.0)
 			}
 
 		case Continuous:
-			raw := value.(float64)
-			normalized := (raw - def.Min) / (def.Max - def.Min)
-			features = append(features, normalized)
+			raw, ok := value.(float64)
+			if !ok {
+				return nil, fmt.Errorf("%w: feature %q expects float64, got %T", ErrBadFeatureType, def.Name, value)
+			}
+			features = append(features, def.scale(raw))
 
 		case Categorical:
-			category := value.(string)
-			for _, cat := range def.Categories {
+			category, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: feature %q expects string, got %T", ErrBadFeatureType, def.Name, value)
+			}
+			if def.Embedding != nil {
+				width := def.embeddingWidth()
+				vec, ok := def.Embedding[category]
+				if !ok {
+					vec = make([]float64, width)
+				}
+				if len(vec) != width {
+					return nil, fmt.Errorf("%w: embedding of %q for feature %q has length %d, expected %d", ErrDimensionMismatch, category, def.Name, len(vec), width)
+				}
+				features = append(features, vec...)
+				break
+			}
+			for _, cat := range def.dummyCategories() {
 				if cat == category {
 					features = append(features, 1.0)
 				} else {
 					features = append(features, 0.0)
 				}
 			}
+
+		case Ordinal:
+			category, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: feature %q expects string, got %T", ErrBadFeatureType, def.Name, value)
+			}
+			position := -1
+			for i, cat := range def.Categories {
+				if cat == category {
+					position = i
+				}
+			}
+			if position < 0 {
+				return nil, fmt.Errorf("ordinal feature %q has no level %q", def.Name, category)
+			}
+			level := 0.0
+			if len(def.Categories) > 1 {
+				level = float64(position) / float64(len(def.Categories)-1)
+			}
+			features = append(features, level)
+
+		case Cyclic:
+			raw, ok := value.(float64)
+			if !ok {
+				return nil, fmt.Errorf("%w: feature %q expects float64, got %T", ErrBadFeatureType, def.Name, value)
+			}
+			if def.Period <= 0 {
+				return nil, fmt.Errorf("cyclic feature %q needs a positive Period", def.Name)
+			}
+			angle := 2 * math.Pi * raw / def.Period
+			features = append(features, math.Sin(angle), math.Cos(angle))
+		}
+	}
+
+	return mat.NewDense(1, len(features), features), nil
+}
+
+func (ni *NeuralInterface) encodeInputs(inputs []map[string]interface{}) (*mat.Dense, error) {
+	x := mat.NewDense(len(inputs), ni.InputWidth(), nil)
+	for i, input := range inputs {
+		row, err := ni.EncodeInput(input)
+		if err != nil {
+			return nil, err
 		}
+		x.SetRow(i, row.RawRowView(0))
 	}
+	return x, nil
+}
 
-	return mat.NewDense(1, len(features), features)
+// EncodeBatch encodes data into one input row and one target row per
+// datum. It returns ErrEmptyData for no data or a schema with no columns,
+// which gonum cannot represent as a matrix.
+func (ni *NeuralInterface) EncodeBatch(data []TrainingDatum) (x, y *mat.Dense, err error) {
+	if len(data) == 0 {
+		return nil, nil, fmt.Errorf("%w: no data to encode", ErrEmptyData)
+	}
+	if ni.InputWidth() == 0 || ni.OutputWidth() == 0 {
+		return nil, nil, fmt.Errorf("%w: the schema encodes no input or output columns", ErrEmptyData)
+	}
+	x = mat.NewDense(len(data), ni.InputWidth(), nil)
+	y = mat.NewDense(len(data), ni.OutputWidth(), nil)
+	for i, d := range data {
+		in, err := ni.EncodeInput(d.Inputs)
+		if err != nil {
+			return nil, nil, err
+		}
+		out, err := ni.EncodeOutput(d.Outputs)
+		if err != nil {
+			return nil, nil, err
+		}
+		x.SetRow(i, in.RawRowView(0))
+		y.SetRow(i, out.RawRowView(0))
+	}
+	return x, y, nil
 }
 
-func (ni *NeuralInterface) EncodeOutput(output map[string]float64) *mat.Dense {
+func (ni *NeuralInterface) EncodeOutput(output map[string]float64) (*mat.Dense, error) {
 	features := make([]float64, 0)
 
 	for _, def := range ni.OutputSchema {
-		value := output[def.Name]
+		value, exists := output[def.Name]
+		if !exists {
+			return nil, fmt.Errorf("output %q is missing from the supplied targets", def.Name)
+		}
+		switch def.Type {
+		case Continuous:
+			lo, hi, err := ni.outputRange(def)
+			if err != nil {
+				return nil, err
+			}
+			if hi != lo {
+				value = (value - lo) / (hi - lo)
+			}
+		case Categorical:
+			index := int(value)
+			if float64(index) != value || index < 0 || index >= len(def.Categories) {
+				return nil, fmt.Errorf("categorical output %q takes a category index below %d, got %v", def.Name, len(def.Categories), value)
+			}
+			for i := range def.Categories {
+				if i == index {
+					features = append(features, 1.0)
+				} else {
+					features = append(features, 0.0)
+				}
+			}
+			continue
+		}
 		features = append(features, value)
 	}
 
-	return mat.NewDense(1, len(features), features)
+	return mat.NewDense(1, len(features), features), nil
 }
 
-func (ni *NeuralInterface) Decode(output *mat.Dense) map[string]float64 {
+// Decode maps one output row back to the schema. A Categorical output
+// yields a "name=category" score per category plus, under its own name, the
+// index of the winning category, the form EncodeOutput takes. The output
+// row of a CrossEntropy net holds logits; PredictDecoded, Classify and
+// PredictCSVStream decode their softmax instead.
+func (ni *NeuralInterface) Decode(output *mat.Dense) (map[string]float64, error) {
+	if _, c := output.Dims(); c != ni.OutputWidth() {
+		return nil, fmt.Errorf("%w: output has %d columns but the schema declares %d", ErrDimensionMismatch, c, ni.OutputWidth())
+	}
+
 	decisions := make(map[string]float64)
 
-	for i, def := range ni.OutputSchema {
-		value := output.At(0, i) 
+	col := 0
+	for _, def := range ni.OutputSchema {
+		value := output.At(0, col) 
 
 		switch def.Type {
+		case Binary:
+			threshold := def.Threshold
+			if threshold == 0 {
+				threshold = 0.5
+			}
+			decisions[def.Name] = 0
+			if value >= threshold {
+				decisions[def.Name] = 1
+			}
 		case Probability:
+			if value < 0 || value > 1 {
+				if ni.StrictProbabilities {
+					return nil, fmt.Errorf("output %q decodes to %v, outside [0, 1]", def.Name, value)
+				}
+				value = math.Max(0, math.Min(1, value))
+			}
 			decisions[def.Name] = value
 		case Continuous:
-			actual := value*(def.Max-def.Min) + def.Min
+			lo, hi, err := ni.outputRange(def)
+			if err != nil {
+				return nil, err
+			}
+			actual := value
+			if hi != lo {
+				actual = value*(hi-lo) + lo
+			}
+			if def.RoundStep > 0 {
+				actual = math.Round(actual/def.RoundStep) * def.RoundStep
+			}
 			decisions[def.Name] = actual
+		case Categorical:
+			scores := output.RawRowView(0)[col : col+len(def.Categories)]
+			for i, cat := range def.Categories {
+				decisions[def.Name+"="+cat] = scores[i]
+			}
+			decisions[def.Name] = float64(floats.MaxIdx(scores))
 		}
+		col += def.width()
 	} 
-	return decisions
+	return decisions, nil
+}
+
+// DecodeCategory returns the winning category of the Categorical output
+// outputName in an output row, together with its score.
+func (ni *NeuralInterface) DecodeCategory(output *mat.Dense, outputName string) (string, float64, error) {
+	if _, c := output.Dims(); c != ni.OutputWidth() {
+		return "", 0, fmt.Errorf("%w: output has %d columns but the schema declares %d", ErrDimensionMismatch, c, ni.OutputWidth())
+	}
+
+	col := 0
+	for _, def := range ni.OutputSchema {
+		if def.Name != outputName {
+			col += def.width()
+			continue
+		}
+		if def.Type != Categorical || len(def.Categories) == 0 {
+			return "", 0, fmt.Errorf("%w: output %q is not Categorical", ErrBadFeatureType, outputName)
+		}
+		scores := output.RawRowView(0)[col : col+len(def.Categories)]
+		best := floats.MaxIdx(scores)
+		return def.Categories[best], scores[best], nil
+	}
+
+	return "", 0, fmt.Errorf("the output schema has no output %q", outputName)
+}
+
+// width is the number of output columns the definition occupies.
+func (def OutputDefinition) width() int {
+	if def.Type == Categorical {
+		return len(def.Categories)
+	}
+	return 1
+}
+
+// outputRange is the Min and Max a Continuous output is scaled by: its own,
+// or those of the input named by ScaleLike. An empty range leaves values
+// unscaled.
+func (ni *NeuralInterface) outputRange(def OutputDefinition) (lo, hi float64, err error) {
+	if def.ScaleLike == "" {
+		return def.Min, def.Max, nil
+	}
+	for _, in := range ni.InputSchema {
+		if in.Name == def.ScaleLike {
+			if in.Type != Continuous {
+				return 0, 0, fmt.Errorf("%w: output %q is scaled like %q, which is not Continuous", ErrBadFeatureType, def.Name, in.Name)
+			}
+			return in.Min, in.Max, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("output %q is scaled like unknown input %q", def.Name, def.ScaleLike)
 }
 
 type TrainingDatum struct {
@@ -0,0 +1,180 @@
+package main
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// classifierNet maps any input to output pre-activations z through a
+// single hidden unit that outputs 1.
+func classifierNet(z []float64, conf NetConfig) *NeuralNet {
+	conf.InputNeurons, conf.HiddenNeurons, conf.OutputNeurons = 1, 1, len(z)
+	nn := NewNet(conf)
+	nn.wHidden = mat.NewDense(1, 1, []float64{0})
+	nn.bHidden = mat.NewDense(1, 1, []float64{1})
+	nn.wOut = mat.NewDense(1, len(z), z)
+	nn.bOut = mat.NewDense(1, len(z), nil)
+	nn.config.HiddenActivations = []Activation{None}
+	return nn
+}
+
+func classifierSchema(names ...string) *NeuralInterface {
+	ni := &NeuralInterface{InputSchema: []FeatureDefinition{{Name: "x", Type: Continuous, Min: 0, Max: 1}}}
+	for _, name := range names {
+		ni.OutputSchema = append(ni.OutputSchema, OutputDefinition{Name: name, Type: Probability})
+	}
+	return ni
+}
+
+func TestClassifyConfidence(t *testing.T) {
+	input := map[string]interface{}{"x": 0.5}
+	tests := []struct {
+		name string
+		z    []float64
+		conf NetConfig
+		want float64
+	}{
+		{"sigmoid outputs near certain", []float64{20, -20}, NetConfig{}, 1},
+		{"sigmoid outputs normalized by their sum", []float64{math.Log(9), 0}, NetConfig{}, 0.9 / 1.4},
+		{"cross-entropy logits", []float64{math.Log(3), 0}, NetConfig{CrossEntropy: true, OutputActivation: None}, 0.75},
+	}
+	for _, tt := range tests {
+		nn := classifierNet(tt.z, tt.conf)
+		label, confidence, probs, err := nn.Classify(classifierSchema("a", "b"), input)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if label != "a" || math.Abs(confidence-tt.want) > 1e-6 {
+			t.Errorf("%s: Classify = %q, %v, want %q, %v", tt.name, label, confidence, "a", tt.want)
+		}
+		if sum := probs["a"] + probs["b"]; math.Abs(sum-1) > 1e-12 {
+			t.Errorf("%s: probabilities sum to %v", tt.name, sum)
+		}
+	}
+}
+
+func TestPredictDecodedCrossEntropy(t *testing.T) {
+	nn := classifierNet([]float64{math.Log(3) + 5, 5}, NetConfig{CrossEntropy: true, OutputActivation: None})
+	ni := classifierSchema("a", "b")
+	ni.StrictProbabilities = true
+	decoded, err := nn.PredictDecoded(ni, map[string]interface{}{"x": 0.5})
+	if err != nil {
+		t.Fatalf("PredictDecoded: %v", err)
+	}
+	if math.Abs(decoded["a"]-0.75) > 1e-12 || math.Abs(decoded["b"]-0.25) > 1e-12 {
+		t.Errorf("PredictDecoded = %v, want a 0.75 and b 0.25", decoded)
+	}
+}
+
+func TestClassifyAbstain(t *testing.T) {
+	ni := classifierSchema("a", "b")
+	ni.AbstainBelow = 0.9
+	input := map[string]interface{}{"x": 0.5}
+
+	confident := classifierNet([]float64{20, -20}, NetConfig{})
+	if label, confidence, _, err := confident.Classify(ni, input); err != nil || label != "a" {
+		t.Errorf("confident Classify = %q, %v, %v, want \"a\"", label, confidence, err)
+	}
+
+	unsure := classifierNet([]float64{0.1, 0}, NetConfig{})
+	if label, confidence, _, err := unsure.Classify(ni, input); err != nil || label != "" {
+		t.Errorf("unsure Classify = %q, %v, %v, want an abstention", label, confidence, err)
+	}
+}
+
+func TestUpdateRatiosZeroWeights(t *testing.T) {
+	zeros := []float64{0, 0, 0, 0}
+	nn := trainedXOR(t, NetConfig{
+		NumEpochs:           3,
+		InitialInputWeights: map[int][]float64{0: zeros, 1: zeros},
+	})
+
+	for _, e := range nn.TrainingReport().Epochs {
+		for name, ratio := range e.UpdateRatios {
+			if math.IsNaN(ratio) || math.IsInf(ratio, 0) {
+				t.Errorf("epoch %d: %s update ratio is %v", e.Epoch, name, ratio)
+			}
+		}
+	}
+}
+
+func TestFitConstantColumn(t *testing.T) {
+	ni := &NeuralInterface{
+		InputSchema: []FeatureDefinition{
+			{Name: "c", Type: Continuous},
+			{Name: "v", Type: Continuous},
+		},
+		OutputSchema: []OutputDefinition{{Name: "y", Type: Continuous}},
+	}
+	var data []TrainingDatum
+	for i := 0; i < 8; i++ {
+		v := float64(i)
+		data = append(data, TrainingDatum{
+			Inputs:  map[string]interface{}{"c": 3.0, "v": v},
+			Outputs: map[string]float64{"y": 2 * v},
+		})
+	}
+
+	nn, report, err := Fit(ni, data, NetConfig{HiddenNeurons: 3, NumEpochs: 20, LearningRate: 0.1, Seed: 1})
+	if err != nil {
+		t.Fatalf("Fit: %v", err)
+	}
+	for _, input := range []map[string]interface{}{{"c": 3.0, "v": 1.0}, {"v": 1.0}} {
+		x, err := ni.EncodeInput(input)
+		if err != nil {
+			t.Fatalf("EncodeInput(%v): %v", input, err)
+		}
+		if got := x.At(0, 0); got != 0.5 {
+			t.Errorf("EncodeInput(%v) encodes the constant column as %v, want 0.5", input, got)
+		}
+		output, err := nn.Predict(x)
+		if err != nil {
+			t.Fatalf("Predict: %v", err)
+		}
+		if got := output.At(0, 0); math.IsNaN(got) {
+			t.Errorf("Predict(%v) = %v, want finite", input, got)
+		}
+	}
+	if loss := report.Epochs[len(report.Epochs)-1].TrainLoss; math.IsNaN(loss) || loss == 0 {
+		t.Errorf("final TrainLoss = %v, want finite and non-zero", loss)
+	}
+}
+
+func TestAdaptiveBatch(t *testing.T) {
+	const n, limit = 512, 512
+	x, y := mat.NewDense(n, 2, nil), mat.NewDense(n, 1, nil)
+	for i := 0; i < n; i++ {
+		a, b := float64(i%32)/32, float64(i/32)/16
+		x.SetRow(i, []float64{a, b})
+		y.Set(i, 0, 0.5+0.4*math.Sin(3*a+2*b)+0.05*math.Sin(float64(i*i)))
+	}
+	conf := NetConfig{InputNeurons: 2, HiddenNeurons: 8, OutputNeurons: 1, NumEpochs: 30, LearningRate: 0.05, Seed: 3, BatchSize: 16}
+	fixed := NewNet(conf)
+	if err := fixed.Train(x, y); err != nil {
+		t.Fatalf("Train: %v", err)
+	}
+	conf.AdaptiveBatchMax = limit
+	adaptive := NewNet(conf)
+	if err := adaptive.Train(x, y); err != nil {
+		t.Fatalf("Train: %v", err)
+	}
+
+	epochs := adaptive.TrainingReport().Epochs
+	prev := conf.BatchSize
+	for _, e := range epochs {
+		if e.BatchSize < prev || e.BatchSize > limit {
+			t.Errorf("epoch %d: batch size %d, want between %d and %d", e.Epoch, e.BatchSize, prev, limit)
+		}
+		prev = e.BatchSize
+	}
+	if prev == conf.BatchSize {
+		t.Errorf("batch size never grew from %d", prev)
+	}
+
+	first, last := epochs[0].TrainLoss, epochs[len(epochs)-1].TrainLoss
+	want := fixed.TrainingReport().Epochs[len(epochs)-1].TrainLoss
+	if last >= first || last > 2*want {
+		t.Errorf("final TrainLoss = %v from %v, want below it and within twice the fixed batch's %v", last, first, want)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// WritePredictionsCSV writes one row per example: the raw input features in
+// InputSchema order followed by the decoded outputs in OutputSchema order.
+func WritePredictionsCSV(w io.Writer, ni *NeuralInterface, inputs []map[string]interface{}, preds []map[string]float64) error {
+	if len(inputs) != len(preds) {
+		return fmt.Errorf("%w: got %d inputs but %d predictions", ErrDimensionMismatch, len(inputs), len(preds))
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(ni.csvHeader()); err != nil {
+		return err
+	}
+
+	for i := range inputs {
+		if err := cw.Write(ni.csvRecord(inputs[i], preds[i])); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteLossCurveCSV writes one row per epoch of report with the columns
+// epoch, train_loss and val_loss, ready for a spreadsheet or plotting tool.
+// The val_loss cell is empty for epochs without a validation split.
+func WriteLossCurveCSV(w io.Writer, report TrainingReport) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"epoch", "train_loss", "val_loss"}); err != nil {
+		return err
+	}
+
+	for _, e := range report.Epochs {
+		val := ""
+		if !math.IsNaN(e.ValLoss) {
+			val = formatCSVValue(e.ValLoss)
+		}
+		if err := cw.Write([]string{strconv.Itoa(e.Epoch), formatCSVValue(e.TrainLoss), val}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// SortByOutput reorders inputs and preds together by the decoded value of
+// outputName, ascending or, with desc, descending. Ties keep their order and
+// predictions lacking the output go last. It panics if the slices differ in
+// length.
+func SortByOutput(inputs []map[string]interface{}, preds []map[string]float64, outputName string, desc bool) {
+	if len(inputs) != len(preds) {
+		panic(fmt.Sprintf("SortByOutput: %d inputs but %d predictions", len(inputs), len(preds)))
+	}
+	sort.Stable(byOutput{inputs, preds, outputName, desc})
+}
+
+type byOutput struct {
+	inputs []map[string]interface{}
+	preds  []map[string]float64
+	name   string
+	desc   bool
+}
+
+func (s byOutput) Len() int { return len(s.preds) }
+
+func (s byOutput) Swap(i, j int) {
+	s.inputs[i], s.inputs[j] = s.inputs[j], s.inputs[i]
+	s.preds[i], s.preds[j] = s.preds[j], s.preds[i]
+}
+
+func (s byOutput) Less(i, j int) bool {
+	a, okA := s.preds[i][s.name]
+	b, okB := s.preds[j][s.name]
+	if !okA || !okB {
+		return okA
+	}
+	if s.desc {
+		return a > b
+	}
+	return a < b
+}
+
+const predictCSVBatchRows = 512
+
+// PredictCSVStream scores the rows of a CSV file with a header line naming
+// the input features, writing the same columns plus the decoded outputs.
+// Rows are read and scored in fixed-size batches so memory use does not grow
+// with the input. Empty cells and absent columns count as missing values.
+func (nn *NeuralNet) PredictCSVStream(ni *NeuralInterface, r io.Reader, w io.Writer) error {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("reading CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[name] = i
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(ni.csvHeader()); err != nil {
+		return err
+	}
+
+	inputs := make([]map[string]interface{}, 0, predictCSVBatchRows)
+	flush := func() error {
+		if len(inputs) == 0 {
+			return nil
+		}
+		x, err := ni.encodeInputs(inputs)
+		if err != nil {
+			return err
+		}
+		output, err := nn.Predict(x)
+		if err != nil {
+			return err
+		}
+		_, numCols := output.Dims()
+		for i, input := range inputs {
+			decoded, err := nn.decode(ni, output.Slice(i, i+1, 0, numCols).(*mat.Dense))
+			if err != nil {
+				return err
+			}
+			if err := cw.Write(ni.csvRecord(input, decoded)); err != nil {
+				return err
+			}
+		}
+		inputs = inputs[:0]
+		return cw.Error()
+	}
+
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		input, err := ni.parseCSVInput(columns, record)
+		if err != nil {
+			line, _ := cr.FieldPos(0)
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		inputs = append(inputs, input)
+
+		if len(inputs) == predictCSVBatchRows {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func (ni *NeuralInterface) parseCSVInput(columns map[string]int, record []string) (map[string]interface{}, error) {
+	input := make(map[string]interface{}, len(ni.InputSchema))
+
+	for _, def := range ni.InputSchema {
+		i, ok := columns[def.Name]
+		if !ok || i >= len(record) || record[i] == "" {
+			continue
+		}
+		field := record[i]
+
+		switch def.Type {
+		case Binary:
+			v, err := strconv.ParseBool(field)
+			if err != nil {
+				return nil, fmt.Errorf("%w: feature %q: %v", ErrBadFeatureType, def.Name, err)
+			}
+			input[def.Name] = v
+		case Continuous, Cyclic:
+			v, err := strconv.ParseFloat(field, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: feature %q: %v", ErrBadFeatureType, def.Name, err)
+			}
+			input[def.Name] = v
+		case Categorical, Ordinal:
+			input[def.Name] = field
+		}
+	}
+
+	return input, nil
+}
+
+func (ni *NeuralInterface) csvHeader() []string {
+	header := make([]string, 0, len(ni.InputSchema)+len(ni.OutputSchema))
+	for _, def := range ni.InputSchema {
+		header = append(header, def.Name)
+	}
+	for _, def := range ni.OutputSchema {
+		header = append(header, def.Name)
+	}
+	return header
+}
+
+func (ni *NeuralInterface) csvRecord(input map[string]interface{}, pred map[string]float64) []string {
+	record := make([]string, 0, len(ni.InputSchema)+len(ni.OutputSchema))
+	for _, def := range ni.InputSchema {
+		record = append(record, formatCSVValue(input[def.Name]))
+	}
+	for _, def := range ni.OutputSchema {
+		value, exists := pred[def.Name]
+		if !exists {
+			record = append(record, "")
+			continue
+		}
+		if i := int(value); def.Type == Categorical && i >= 0 && i < len(def.Categories) {
+			record = append(record, def.Categories[i])
+			continue
+		}
+		record = append(record, strconv.FormatFloat(value, 'g', -1, 64))
+	}
+	return record
+}
+
+func formatCSVValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
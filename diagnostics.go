@@ -0,0 +1,656 @@
+package main
+import (
+	"fmt"
+	"maps"
+	"math"
+	"math/rand"
+	"slices"
+	"time"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// DiagnoseOutputs returns the variance of each output neuron over the rows of
+// x. Values near zero point at dead units or a labeling problem. The map is
+// nil if the net cannot predict yet.
+func (nn *NeuralNet) DiagnoseOutputs(x *mat.Dense) map[int]float64 {
+	output, err := nn.Predict(x)
+	if err != nil {
+		return nil
+	}
+
+	_, numCols := output.Dims()
+	variances := make(map[int]float64, numCols)
+	for j := 0; j < numCols; j++ {
+		_, variance := stat.PopMeanVariance(mat.Col(nil, j, output), nil)
+		variances[j] = variance
+	}
+
+	return variances
+}
+
+// OutputEntropy returns the Shannon entropy, in nats, of the softmax of
+// each row's outputs, as a column with one row per row of x. It is highest
+// where the net is least decided between outputs, which makes it a ranking
+// for picking examples to label next.
+func (nn *NeuralNet) OutputEntropy(x *mat.Dense) (*mat.Dense, error) {
+	output, err := nn.Predict(x)
+	if err != nil {
+		return nil, err
+	}
+
+	numRows, _ := output.Dims()
+	entropy := mat.NewDense(numRows, 1, nil)
+	for i := 0; i < numRows; i++ {
+		var h float64
+		for _, p := range softmax(output.RawRowView(i)) {
+			if p > 0 {
+				h -= p * math.Log(p)
+			}
+		}
+		entropy.Set(i, 0, h)
+	}
+
+	return entropy, nil
+}
+
+// PerSampleLoss returns the training loss of each row of x against y, in
+// row order, for error analysis; sort the row indices by it to find the
+// hardest examples. With every target labelled, the mean of the result is
+// the loss reported for the whole set. NaN targets break that: a row's loss
+// averages only its labelled outputs, while the set's loss averages over
+// all labelled cells, and a row with nothing labelled (for CrossEntropy,
+// any NaN target) scores 0 where the set's loss leaves it out.
+func (nn *NeuralNet) PerSampleLoss(x, y *mat.Dense) ([]float64, error) {
+	if err := nn.checkTrainingData(x, y); err != nil {
+		return nil, err
+	}
+	output, err := nn.Predict(x)
+	if err != nil {
+		return nil, err
+	}
+
+	numRows, numCols := y.Dims()
+	losses := make([]float64, numRows)
+	for i := range losses {
+		losses[i] = nn.loss(y.Slice(i, i+1, 0, numCols), output.Slice(i, i+1, 0, numCols))
+	}
+
+	return losses, nil
+}
+
+// ShapValues estimates the Shapley value of each input feature for the
+// prediction on input by permutation sampling: each of samples draws a
+// background datum and a feature order, then switches the features from the
+// background's values to input's one at a time in that order, crediting each
+// feature with the change in output it causes. The values of each output
+// column sum to its prediction on input minus its mean prediction on the
+// drawn background rows. Keys are feature names for a single output column
+// and "column/feature" otherwise. The draws use Seed, or the clock when it
+// is 0. The map is nil if there is no background, no sample or the net
+// cannot predict.
+func (nn *NeuralNet) ShapValues(ni *NeuralInterface, input map[string]interface{}, background []TrainingDatum, samples int) map[string]float64 {
+	if len(background) == 0 || samples < 1 {
+		return nil
+	}
+	seed := nn.config.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	randGen := rand.New(rand.NewSource(seed))
+
+	features := ni.InputSchema
+	columns := ni.OutputColumns()
+	totals := make([][]float64, len(features))
+	for f := range totals {
+		totals[f] = make([]float64, len(columns))
+	}
+
+	for s := 0; s < samples; s++ {
+		base := background[randGen.Intn(len(background))].Inputs
+		mixed := make(map[string]interface{}, len(features))
+		for _, def := range features {
+			mixed[def.Name] = base[def.Name]
+		}
+
+		order := randGen.Perm(len(features))
+		steps := make([]map[string]interface{}, 0, len(order)+1)
+		steps = append(steps, maps.Clone(mixed))
+		for _, f := range order {
+			mixed[features[f].Name] = input[features[f].Name]
+			steps = append(steps, maps.Clone(mixed))
+		}
+
+		x, err := ni.encodeInputs(steps)
+		if err != nil {
+			return nil
+		}
+		output, err := nn.Predict(x)
+		if err != nil {
+			return nil
+		}
+		for i, f := range order {
+			for k := range columns {
+				totals[f][k] += output.At(i+1, k) - output.At(i, k)
+			}
+		}
+	}
+
+	values := make(map[string]float64, len(features)*len(columns))
+	for f, def := range features {
+		for k, col := range columns {
+			key := col + "/" + def.Name
+			if len(columns) == 1 {
+				key = def.Name
+			}
+			values[key] = totals[f][k] / float64(samples)
+		}
+	}
+
+	return values
+}
+
+// PartialDependence sweeps the input feature featureName over its range
+// and returns, for each value xs[i], the net's prediction averaged over
+// data with the feature set to xs[i] in every row and the other features
+// as they are. Continuous features take points evenly spaced values from
+// Min to Max and Cyclic ones points values over one Period; Binary
+// features take 0 and 1, and Categorical and Ordinal features each
+// category's index, regardless of points. The net must have a single
+// output column.
+func (nn *NeuralNet) PartialDependence(ni *NeuralInterface, data []TrainingDatum, featureName string, points int) (xs, ys []float64, err error) {
+	if len(data) == 0 {
+		return nil, nil, fmt.Errorf("%w: no data to average over", ErrEmptyData)
+	}
+	if nn.config.OutputNeurons != 1 {
+		return nil, nil, fmt.Errorf("%w: partial dependence needs a single output column, the net has %d", ErrDimensionMismatch, nn.config.OutputNeurons)
+	}
+	i := slices.IndexFunc(ni.InputSchema, func(def FeatureDefinition) bool { return def.Name == featureName })
+	if i < 0 {
+		return nil, nil, fmt.Errorf("the input schema has no feature %q", featureName)
+	}
+	def := ni.InputSchema[i]
+
+	var values []interface{}
+	switch def.Type {
+	case Continuous, Cyclic:
+		if points < 2 {
+			return nil, nil, fmt.Errorf("partial dependence needs at least 2 points, got %d", points)
+		}
+		for p := 0; p < points; p++ {
+			v := def.Min + (def.Max-def.Min)*float64(p)/float64(points-1)
+			if def.Type == Cyclic {
+				v = def.Period * float64(p) / float64(points)
+			}
+			xs, values = append(xs, v), append(values, v)
+		}
+	case Binary:
+		xs, values = []float64{0, 1}, []interface{}{false, true}
+	case Categorical, Ordinal:
+		for c, cat := range def.Categories {
+			xs, values = append(xs, float64(c)), append(values, cat)
+		}
+	}
+
+	ys = make([]float64, len(xs))
+	inputs := make([]map[string]interface{}, len(data))
+	for p, v := range values {
+		for r, d := range data {
+			inputs[r] = maps.Clone(d.Inputs)
+			inputs[r][featureName] = v
+		}
+		x, err := ni.encodeInputs(inputs)
+		if err != nil {
+			return nil, nil, err
+		}
+		output, err := nn.Predict(x)
+		if err != nil {
+			return nil, nil, err
+		}
+		ys[p] = stat.Mean(output.RawMatrix().Data, nil)
+	}
+
+	return xs, ys, nil
+}
+
+// interactionStep is the finite-difference step of InteractionStrength on
+// the encoded inputs, most of which lie in [0, 1].
+const interactionStep = 1e-2
+
+// InteractionStrength estimates how strongly each pair of input features
+// interacts in the net's predictions on data: the root mean square, over
+// the rows of data and the output columns, of the mixed second derivative
+// of the output by the two features' encoded columns, from central finite
+// differences. Entry (a, b) of the symmetric result is zero for features
+// the net combines additively and grows with how much one feature changes
+// the effect of the other; for features encoding to several columns it is
+// the largest over their pairs of columns. names lists the features in
+// InputSchema order, labelling both rows and columns. It predicts on data
+// four times per pair of input columns.
+func (nn *NeuralNet) InteractionStrength(ni *NeuralInterface, data []TrainingDatum) (names []string, strength *mat.Dense, err error) {
+	if len(data) == 0 {
+		return nil, nil, fmt.Errorf("%w: no data to differentiate on", ErrEmptyData)
+	}
+	inputs := make([]map[string]interface{}, len(data))
+	for i, d := range data {
+		inputs[i] = d.Inputs
+	}
+	x, err := ni.encodeInputs(inputs)
+	if err != nil {
+		return nil, nil, err
+	}
+	numRows, numCols := x.Dims()
+
+	// the feature behind each encoded column
+	feature := make([]int, 0, numCols)
+	names = make([]string, len(ni.InputSchema))
+	for f, def := range ni.InputSchema {
+		names[f] = def.Name
+		single := &NeuralInterface{InputSchema: []FeatureDefinition{def}}
+		for range single.InputColumns() {
+			feature = append(feature, f)
+		}
+	}
+
+	h := interactionStep
+	shifted := func(a, b int, da, db float64) (*mat.Dense, error) {
+		m := mat.DenseCopyOf(x)
+		for i := 0; i < numRows; i++ {
+			m.Set(i, a, m.At(i, a)+da)
+			m.Set(i, b, m.At(i, b)+db)
+		}
+		return nn.Predict(m)
+	}
+
+	strength = mat.NewDense(len(names), len(names), nil)
+	for a := 0; a < numCols; a++ {
+		for b := a + 1; b < numCols; b++ {
+			fa, fb := feature[a], feature[b]
+			if fa == fb {
+				continue
+			}
+
+			var corners [4]*mat.Dense
+			for c, d := range [4][2]float64{{h, h}, {h, -h}, {-h, h}, {-h, -h}} {
+				if corners[c], err = shifted(a, b, d[0], d[1]); err != nil {
+					return nil, nil, err
+				}
+			}
+			mixed := new(mat.Dense)
+			mixed.Sub(corners[0], corners[1])
+			mixed.Sub(mixed, corners[2])
+			mixed.Add(mixed, corners[3])
+
+			_, numOut := mixed.Dims()
+			rms := mat.Norm(mixed, 2) / (4 * h * h) / math.Sqrt(float64(numRows*numOut))
+			if rms > strength.At(fa, fb) {
+				strength.Set(fa, fb, rms)
+				strength.Set(fb, fa, rms)
+			}
+		}
+	}
+
+	return names, strength, nil
+}
+
+// PermutationImportance measures how much each input column of x matters
+// to the loss on y. For every column it shuffles that column repeats times,
+// keeping the others in place; importance is the mean rise in loss over the
+// shuffles. The p-value tests that rise against a null distribution: each
+// shuffle's rise is also measured against a random reordering of the rows
+// of y, which no column can predict, and the p-value is (1 + null rises at
+// least the importance) / (1 + repeats). A column the net leans on without
+// it carrying signal raises the loss under the null as much, so it is not
+// found significant. A zero seed seeds from the clock.
+func (nn *NeuralNet) PermutationImportance(x, y *mat.Dense, repeats int, seed int64) (importance, pValues []float64, err error) {
+	if repeats < 1 {
+		return nil, nil, fmt.Errorf("permutation importance needs at least 1 repeat, got %d", repeats)
+	}
+	if err := nn.checkTrainingData(x, y); err != nil {
+		return nil, nil, err
+	}
+
+	output, err := nn.Predict(x)
+	if err != nil {
+		return nil, nil, err
+	}
+	baseline := nn.loss(y, output)
+
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	randGen := rand.New(rand.NewSource(seed))
+
+	numRows, numCols := x.Dims()
+	importance = make([]float64, numCols)
+	pValues = make([]float64, numCols)
+	shuffled := mat.DenseCopyOf(x)
+	column := make([]float64, numRows)
+	nullRises := make([]float64, repeats)
+	for j := 0; j < numCols; j++ {
+		mat.Col(column, j, x)
+		for r := 0; r < repeats; r++ {
+			randGen.Shuffle(numRows, func(a, b int) { column[a], column[b] = column[b], column[a] })
+			shuffled.SetCol(j, column)
+
+			shuffledOutput, err := nn.Predict(shuffled)
+			if err != nil {
+				return nil, nil, err
+			}
+			importance[j] += nn.loss(y, shuffledOutput) - baseline
+
+			yNull := selectRows(y, randGen.Perm(numRows))
+			nullRises[r] = nn.loss(yNull, shuffledOutput) - nn.loss(yNull, output)
+		}
+		shuffled.SetCol(j, mat.Col(column, j, x))
+
+		importance[j] /= float64(repeats)
+		atLeast := 0
+		for _, rise := range nullRises {
+			if rise >= importance[j] {
+				atLeast++
+			}
+		}
+		pValues[j] = float64(1+atLeast) / float64(1+repeats)
+	}
+
+	return importance, pValues, nil
+}
+
+// ReconstructionError returns, for a net trained to reproduce its inputs
+// (see FitAutoencoder), the mean squared difference between each row of x
+// and its reconstruction, as a column with one row per row of x. Rows unlike
+// the training data reconstruct poorly, so it serves as an anomaly score.
+func (nn *NeuralNet) ReconstructionError(x *mat.Dense) (*mat.Dense, error) {
+	if nn.config.InputNeurons != nn.config.OutputNeurons {
+		return nil, fmt.Errorf("%w: reconstruction needs as many outputs as inputs, the net has %d and %d", ErrDimensionMismatch, nn.config.OutputNeurons, nn.config.InputNeurons)
+	}
+	output, err := nn.Predict(x)
+	if err != nil {
+		return nil, err
+	}
+
+	numRows, _ := x.Dims()
+	errs := mat.NewDense(numRows, 1, nil)
+	for i := 0; i < numRows; i++ {
+		errs.Set(i, 0, meanSquaredError(x.Slice(i, i+1, 0, nn.config.InputNeurons), output.Slice(i, i+1, 0, nn.config.OutputNeurons)))
+	}
+
+	return errs, nil
+}
+
+// LossLandscape returns the loss on x and y at weights + alpha·direction
+// for each of alphas, where direction is a random Gaussian direction (drawn
+// from seed; 0 seeds from the clock) rescaled, per parameter matrix, to the
+// norm of that matrix so that alpha is relative to the weights' own scale.
+// A sharp minimum shows as a narrow dip around alpha 0. The weights are
+// swapped out while it runs and restored on return, so the net must not be
+// used concurrently.
+func (nn *NeuralNet) LossLandscape(x, y *mat.Dense, alphas []float64, seed int64) ([]float64, error) {
+	if nn.wHidden == nil || nn.wOut == nil {
+		return nil, fmt.Errorf("%w: the supplied weights are empty", ErrNotTrained)
+	}
+	if err := nn.checkTrainingData(x, y); err != nil {
+		return nil, err
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	randGen := rand.New(rand.NewSource(seed))
+
+	fields := []**mat.Dense{&nn.wHidden, &nn.bHidden, &nn.wOut, &nn.bOut}
+	if nn.slopes != nil {
+		fields = append(fields, &nn.slopes)
+	}
+	trained := make([]*mat.Dense, len(fields))
+	directions := make([]*mat.Dense, len(fields))
+	for i, f := range fields {
+		trained[i] = *f
+		d := mat.DenseCopyOf(trained[i])
+		d.Apply(func(_, _ int, _ float64) float64 { return randGen.NormFloat64() }, d)
+		if norm := mat.Norm(d, 2); norm > 0 {
+			d.Scale(mat.Norm(trained[i], 2)/norm, d)
+		}
+		directions[i] = d
+	}
+	defer func() {
+		for i, f := range fields {
+			*f = trained[i]
+		}
+	}()
+
+	losses := make([]float64, len(alphas))
+	for a, alpha := range alphas {
+		for i, f := range fields {
+			moved := new(mat.Dense)
+			moved.Scale(alpha, directions[i])
+			moved.Add(trained[i], moved)
+			*f = moved
+		}
+		output, err := nn.Predict(x)
+		if err != nil {
+			return nil, err
+		}
+		losses[a] = nn.loss(y, output)
+	}
+
+	return losses, nil
+}
+
+// InputGradient returns the Jacobian of the outputs with respect to the raw
+// inputs at the single example x: entry (k, i) is the derivative of output
+// k by input column i, through the fitted scaler.
+func (nn *NeuralNet) InputGradient(x *mat.Dense) (*mat.Dense, error) {
+	if nn.wHidden == nil || nn.wOut == nil {
+		return nil, fmt.Errorf("%w: the supplied weights are empty", ErrNotTrained)
+	}
+	if r, c := x.Dims(); r != 1 || c != nn.config.InputNeurons {
+		return nil, fmt.Errorf("%w: input is %dx%d, expected 1x%d", ErrDimensionMismatch, r, c, nn.config.InputNeurons)
+	}
+
+	var in mat.Matrix = x
+	if nn.scaler != nil {
+		in = nn.scaler.transform(x)
+	}
+	hidden, output := new(mat.Dense), new(mat.Dense)
+	nn.forwardInto(output, hidden, in)
+
+	// dOutput/dHidden, one row per output
+	local := mat.DenseCopyOf(nn.wOut.T())
+	local.Apply(func(k, j int, v float64) float64 {
+		return v * nn.config.OutputActivation.prime(output.At(0, k)) * nn.hiddenPrime(nn.slopes, j, hidden.At(0, j))
+	}, local)
+
+	// then through the hidden weights and the scaler
+	jacobian := new(mat.Dense)
+	jacobian.Mul(local, nn.wHidden.T())
+	if nn.scaler != nil {
+		jacobian.Apply(func(_, i int, v float64) float64 {
+			return v / nn.scaler.Scale[i]
+		}, jacobian)
+	}
+
+	return jacobian, nil
+}
+
+// JacobianCondition returns the 2-norm condition number of InputGradient
+// at the single example x, the ratio of its largest singular value to its
+// smallest. A large value marks an input where a small perturbation in some
+// direction moves the outputs far more than in others; a Jacobian with a
+// zero singular value gives +Inf.
+func (nn *NeuralNet) JacobianCondition(x *mat.Dense) (float64, error) {
+	jacobian, err := nn.InputGradient(x)
+	if err != nil {
+		return 0, err
+	}
+
+	var svd mat.SVD
+	if !svd.Factorize(jacobian, mat.SVDNone) {
+		return 0, fmt.Errorf("singular value decomposition of the Jacobian failed")
+	}
+	values := svd.Values(nil)
+	smallest := values[len(values)-1]
+	if smallest == 0 {
+		return math.Inf(1), nil
+	}
+	return values[0] / smallest, nil
+}
+
+// FGSM builds fast-gradient-sign adversarial examples: every row of x is
+// moved by epsilon, in raw input units, along the sign of the gradient of
+// its loss against y with respect to the inputs, the direction that raises
+// the loss fastest. Compare the loss or accuracy on the result with that on
+// x to measure how brittle the net is.
+func (nn *NeuralNet) FGSM(x, y *mat.Dense, epsilon float64) (*mat.Dense, error) {
+	if nn.wHidden == nil || nn.wOut == nil {
+		return nil, fmt.Errorf("%w: the supplied weights are empty", ErrNotTrained)
+	}
+	if err := nn.checkTrainingData(x, y); err != nil {
+		return nil, err
+	}
+
+	grad := nn.lossInputGradient(x, y)
+	adversarial := mat.DenseCopyOf(x)
+	adversarial.Apply(func(i, j int, v float64) float64 {
+		switch g := grad.At(i, j); {
+		case g > 0:
+			return v + epsilon
+		case g < 0:
+			return v - epsilon
+		}
+		return v
+	}, adversarial)
+
+	return adversarial, nil
+}
+
+// InvertTo searches for inputs the net maps to target, one per row,
+// starting every input column at 0.5, the middle of EncodeInput's range,
+// and taking steps gradient-descent steps of size lr on the training loss
+// between the output and target. The result is one input that produces
+// target, not necessarily a natural-looking one. If the output stalls away
+// from target, lower lr: a large step can overshoot into the flat tail of
+// a sigmoid, where the gradient all but vanishes.
+func (nn *NeuralNet) InvertTo(target *mat.Dense, steps int, lr float64) (*mat.Dense, error) {
+	if nn.wHidden == nil || nn.wOut == nil {
+		return nil, fmt.Errorf("%w: the supplied weights are empty", ErrNotTrained)
+	}
+	numRows, _ := target.Dims()
+	x := mat.NewDense(numRows, nn.config.InputNeurons, nil)
+	x.Apply(func(_, _ int, _ float64) float64 { return 0.5 }, x)
+	if err := nn.checkTrainingData(x, target); err != nil {
+		return nil, err
+	}
+
+	for s := 0; s < steps; s++ {
+		grad := nn.lossInputGradient(x, target)
+		grad.Scale(lr, grad)
+		x.Sub(x, grad)
+	}
+
+	return x, nil
+}
+
+// lossInputGradient is the gradient of the summed training loss of x
+// against y with respect to the raw inputs, one row per row of x: the
+// backward pass of gradients carried one layer further down, through the
+// scaler. It takes the same loss scaling as Gradients.
+func (nn *NeuralNet) lossInputGradient(x, y *mat.Dense) *mat.Dense {
+	var in mat.Matrix = x
+	if nn.scaler != nil {
+		in = nn.scaler.transform(x)
+	}
+	hidden, output := new(mat.Dense), new(mat.Dense)
+	nn.forwardInto(output, hidden, in)
+
+	// like dOutput in gradients this points down the loss
+	dOutput := nn.outputError(y, output)
+	dOutput.Apply(func(i, k int, v float64) float64 {
+		return v * nn.config.OutputActivation.prime(output.At(i, k))
+	}, dOutput)
+	dHidden := new(mat.Dense)
+	dHidden.Mul(dOutput, nn.wOut.T())
+	dHidden.Apply(func(i, j int, v float64) float64 {
+		return v * nn.hiddenPrime(nn.slopes, j, hidden.At(i, j))
+	}, dHidden)
+
+	grad := new(mat.Dense)
+	grad.Mul(dHidden, nn.wHidden.T())
+	grad.Apply(func(_, col int, v float64) float64 {
+		if nn.scaler != nil {
+			v /= nn.scaler.Scale[col]
+		}
+		return -v
+	}, grad)
+	return grad
+}
+
+// HiddenWeights returns a copy of the input-to-hidden weights, one row per
+// input column and one column per hidden unit, together with the name of
+// each row from ni.InputColumns, ready to plot as a labelled heatmap.
+func (nn *NeuralNet) HiddenWeights(ni *NeuralInterface) (rows []string, weights *mat.Dense, err error) {
+	if nn.wHidden == nil {
+		return nil, nil, fmt.Errorf("%w: the supplied weights are empty", ErrNotTrained)
+	}
+	rows = ni.InputColumns()
+	if len(rows) != nn.config.InputNeurons {
+		return nil, nil, fmt.Errorf("%w: schema encodes %d input columns but InputNeurons is %d", ErrDimensionMismatch, len(rows), nn.config.InputNeurons)
+	}
+
+	return rows, mat.DenseCopyOf(nn.wHidden), nil
+}
+
+// HiddenPreActivations returns the hidden layer's inputs, x·wHidden +
+// bHidden after normalization, one row per row of x. Values far from zero
+// sit in the flat tails of a sigmoid or tanh, where the layer learns
+// slowly.
+func (nn *NeuralNet) HiddenPreActivations(x *mat.Dense) (*mat.Dense, error) {
+	if nn.wHidden == nil || nn.bHidden == nil {
+		return nil, fmt.Errorf("%w: the supplied weights are empty", ErrNotTrained)
+	}
+	if _, c := x.Dims(); c != nn.config.InputNeurons {
+		return nil, fmt.Errorf("%w: input has %d columns but InputNeurons is %d", ErrDimensionMismatch, c, nn.config.InputNeurons)
+	}
+
+	var in mat.Matrix = x
+	if nn.scaler != nil {
+		in = nn.scaler.transform(x)
+	}
+
+	pre := new(mat.Dense)
+	pre.Mul(in, nn.wHidden)
+	pre.Apply(func(_, col int, v float64) float64 { return v + nn.bHidden.At(0, col) }, pre)
+
+	return pre, nil
+}
+
+// DecisionGrid evaluates a two-input net on a steps×steps grid spanning
+// [xMin, xMax] for the first input and [yMin, yMax] for the second, both
+// ends included. Row i*steps+j holds the outputs at the j-th x and i-th y
+// value; for a single output,
+// mat.NewDense(steps, steps, grid.RawMatrix().Data) is the heatmap with y
+// down the rows.
+func (nn *NeuralNet) DecisionGrid(xMin, xMax, yMin, yMax float64, steps int) (*mat.Dense, error) {
+	if nn.config.InputNeurons != 2 {
+		return nil, fmt.Errorf("%w: a decision grid needs 2 inputs, the net has %d", ErrDimensionMismatch, nn.config.InputNeurons)
+	}
+	if steps < 2 {
+		return nil, fmt.Errorf("a decision grid needs at least 2 steps, got %d", steps)
+	}
+
+	xs := make([]float64, steps)
+	ys := make([]float64, steps)
+	floats.Span(xs, xMin, xMax)
+	floats.Span(ys, yMin, yMax)
+
+	points := mat.NewDense(steps*steps, 2, nil)
+	for i, y := range ys {
+		for j, x := range xs {
+			points.SetRow(i*steps+j, []float64{x, y})
+		}
+	}
+
+	return nn.Predict(points)
+}
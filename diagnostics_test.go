@@ -0,0 +1,39 @@
+package main
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// The second column is noise the net has fit; shuffling it raises the
+// training loss on every repeat, but no more than under the null.
+func TestPermutationImportancePValues(t *testing.T) {
+	randGen := rand.New(rand.NewSource(1))
+	const n = 40
+	x, y := mat.NewDense(n, 2, nil), mat.NewDense(n, 1, nil)
+	for i := 0; i < n; i++ {
+		a, b := randGen.Float64(), randGen.Float64()
+		x.SetRow(i, []float64{a, b})
+		y.Set(i, 0, 0.5+0.3*math.Sin(4*a)+0.1*randGen.NormFloat64())
+	}
+	nn := NewNet(NetConfig{InputNeurons: 2, HiddenNeurons: 32, OutputNeurons: 1, NumEpochs: 1000, LearningRate: 0.01, Seed: 2, Optimizer: OptimizerConfig{Kind: Adam}})
+	if err := nn.Train(x, y); err != nil {
+		t.Fatalf("Train: %v", err)
+	}
+
+	importance, pValues, err := nn.PermutationImportance(x, y, 99, 5)
+	if err != nil {
+		t.Fatalf("PermutationImportance: %v", err)
+	}
+	if importance[1] <= 0 {
+		t.Fatalf("noise column importance = %v, want the net to have fit it", importance[1])
+	}
+	if pValues[0] > 0.05 {
+		t.Errorf("signal column p-value = %v, want at most 0.05", pValues[0])
+	}
+	if pValues[1] <= 0.05 {
+		t.Errorf("noise column p-value = %v, want above 0.05", pValues[1])
+	}
+}
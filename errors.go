@@ -0,0 +1,13 @@
+package main
+import (
+	"errors"
+)
+
+// Sentinel errors wrapped by the package's failures; match them with
+// errors.Is.
+var (
+	ErrNotTrained        = errors.New("net is not trained")
+	ErrDimensionMismatch = errors.New("dimension mismatch")
+	ErrBadFeatureType    = errors.New("bad feature type")
+	ErrEmptyData         = errors.New("empty data")
+)
@@ -1,3 +1,5 @@
 module github.com/nate-telecomm/egnn
 
 go 1.25.1
+
+require gonum.org/v1/gonum v0.17.0
@@ -0,0 +1,177 @@
+package main
+import (
+	"math"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+// loss is the training objective reported per epoch: squared error, or the
+// pinball loss for quantile outputs, averaged over every cell with each
+// column scaled by OutputLossWeights when set. Squared error ignores the
+// first LossEpsilon of each residual. With CrossEntropy it is the mean
+// cross-entropy per row instead. NaN targets mark missing labels and are
+// left out, along with the rest of their row for CrossEntropy.
+func (nn *NeuralNet) loss(target, output mat.Matrix) float64 {
+	numRows, numCols := target.Dims()
+
+	if nn.config.CrossEntropy {
+		var sum float64
+		labelled := 0
+		t, z := make([]float64, numCols), make([]float64, numCols)
+		for i := 0; i < numRows; i++ {
+			if mat.Row(t, i, target); floats.HasNaN(t) {
+				continue
+			}
+			sum += crossEntropy(t, mat.Row(z, i, output))
+			labelled++
+		}
+		return sum / float64(max(labelled, 1))
+	}
+
+	var sum float64
+	labelled := 0
+	for j := 0; j < numCols; j++ {
+		weight, tau := nn.outputLossWeight(j), nn.outputQuantile(j)
+		for i := 0; i < numRows; i++ {
+			if math.IsNaN(target.At(i, j)) {
+				continue
+			}
+			labelled++
+			d := target.At(i, j) - output.At(i, j)
+			if tau > 0 {
+				sum += weight * pinball(tau, d)
+			} else {
+				d = shrink(d, nn.config.LossEpsilon)
+				sum += weight * d * d
+			}
+		}
+	}
+
+	return sum / float64(max(labelled, 1))
+}
+
+// outputError is the error signal pushed back through the output layer: the
+// residual for squared error, shrunk by LossEpsilon, or the negative
+// pinball subgradient for quantile outputs, scaled by OutputLossWeights.
+// With CrossEntropy it is target - softmax(logits), the negative
+// cross-entropy gradient. It is zero where loss leaves a NaN target out.
+func (nn *NeuralNet) outputError(target, output *mat.Dense) *mat.Dense {
+	networkError := new(mat.Dense)
+
+	if nn.config.CrossEntropy {
+		numRows, _ := output.Dims()
+		networkError.CloneFrom(target)
+		for i := 0; i < numRows; i++ {
+			row := networkError.RawRowView(i)
+			if floats.HasNaN(row) {
+				zeroRow(networkError, i)
+				continue
+			}
+			floats.Sub(row, softmax(output.RawRowView(i)))
+		}
+		return networkError
+	}
+
+	networkError.Sub(target, output)
+
+	networkError.Apply(func(row, col int, v float64) float64 {
+		if math.IsNaN(target.At(row, col)) {
+			return 0
+		}
+		if tau := nn.outputQuantile(col); tau > 0 {
+			switch {
+			case v > 0:
+				v = tau
+			case v < 0:
+				v = tau - 1
+			}
+		} else {
+			v = shrink(v, nn.config.LossEpsilon)
+		}
+		return v * nn.outputLossWeight(col)
+	}, networkError)
+
+	return networkError
+}
+
+func (nn *NeuralNet) outputLossWeight(col int) float64 {
+	if nn.config.OutputLossWeights == nil {
+		return 1
+	}
+	return nn.config.OutputLossWeights[col]
+}
+
+func (nn *NeuralNet) outputQuantile(col int) float64 {
+	if nn.config.OutputQuantiles == nil {
+		return 0
+	}
+	return nn.config.OutputQuantiles[col]
+}
+
+// shrink moves residual towards zero by epsilon, stopping at zero; it is
+// the identity for epsilon 0. A NaN residual stays NaN so a diverged net
+// does not look like a perfect one.
+func shrink(residual, epsilon float64) float64 {
+	switch {
+	case residual > epsilon:
+		return residual - epsilon
+	case residual < -epsilon:
+		return residual + epsilon
+	case math.IsNaN(residual):
+		return residual
+	}
+	return 0
+}
+
+// pinball is the quantile loss of residual target - prediction.
+func pinball(tau, residual float64) float64 {
+	if residual >= 0 {
+		return tau * residual
+	}
+	return (tau - 1) * residual
+}
+
+// logSumExp is log Σ exp(v) computed without overflow: the largest value is
+// factored out, so every exponent is at most 0 and the sum at least 1.
+func logSumExp(values []float64) float64 {
+	max := floats.Max(values)
+	if math.IsInf(max, 0) {
+		return max
+	}
+
+	var sum float64
+	for _, v := range values {
+		sum += math.Exp(v - max)
+	}
+	return max + math.Log(sum)
+}
+
+// crossEntropy is -Σ target·log softmax(logits), using log softmax(z)_j =
+// z_j - logSumExp(z) so that neither large nor very negative logits turn
+// the result into Inf or NaN. Classes with zero target are skipped.
+func crossEntropy(target, logits []float64) float64 {
+	lse := logSumExp(logits)
+
+	var sum float64
+	for j, t := range target {
+		if t != 0 {
+			sum -= t * (logits[j] - lse)
+		}
+	}
+	return sum
+}
+
+func meanSquaredError(target, output mat.Matrix) float64 {
+	numRows, numCols := target.Dims()
+
+	var sum float64
+	for i := 0; i < numRows; i++ {
+		for j := 0; j < numCols; j++ {
+			d := target.At(i, j) - output.At(i, j)
+			sum += d * d
+		}
+	}
+
+	return sum / float64(numRows*numCols)
+}
@@ -0,0 +1,53 @@
+package main
+import (
+	"math"
+	"testing"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestCrossEntropyExtremeLogits(t *testing.T) {
+	nn := NewNet(NetConfig{InputNeurons: 1, OutputNeurons: 3, CrossEntropy: true, OutputActivation: None})
+	logits := mat.NewDense(1, 3, []float64{1e5, -1e5, 3e4})
+	target := mat.NewDense(1, 3, []float64{0, 1, 0})
+
+	loss := nn.loss(target, logits)
+	if math.IsNaN(loss) || math.IsInf(loss, 0) {
+		t.Fatalf("loss = %v, want finite", loss)
+	}
+	if want := 2e5; math.Abs(loss-want) > 1e-9*want {
+		t.Errorf("loss = %v, want %v", loss, want)
+	}
+
+	grad := nn.outputError(target, logits)
+	if row := grad.RawRowView(0); floats.HasNaN(row) || math.IsInf(floats.Norm(row, 2), 0) {
+		t.Errorf("outputError = %v, want finite", row)
+	} else if want := []float64{-1, 1, 0}; !floats.EqualApprox(row, want, 1e-12) {
+		t.Errorf("outputError = %v, want %v", row, want)
+	}
+}
+
+func TestLossNaNOutput(t *testing.T) {
+	target := mat.NewDense(2, 1, []float64{0, 1})
+	output := mat.NewDense(2, 1, []float64{math.NaN(), 1})
+	for _, eps := range []float64{0, 0.1} {
+		nn := NewNet(NetConfig{InputNeurons: 1, OutputNeurons: 1, LossEpsilon: eps})
+		if loss := nn.loss(target, output); !math.IsNaN(loss) {
+			t.Errorf("LossEpsilon %v: loss = %v, want NaN", eps, loss)
+		}
+		if grad := nn.outputError(target, output); !math.IsNaN(grad.At(0, 0)) {
+			t.Errorf("LossEpsilon %v: outputError = %v, want NaN", eps, grad.At(0, 0))
+		}
+	}
+}
+
+func TestNaNNetReportsNaNLoss(t *testing.T) {
+	nan := []float64{math.NaN(), math.NaN(), math.NaN(), math.NaN()}
+	nn := trainedXOR(t, NetConfig{NumEpochs: 2, InitialInputWeights: map[int][]float64{0: nan}})
+	for _, e := range nn.TrainingReport().Epochs {
+		if !math.IsNaN(e.TrainLoss) {
+			t.Errorf("epoch %d: TrainLoss = %v from NaN weights, want NaN", e.Epoch, e.TrainLoss)
+		}
+	}
+}
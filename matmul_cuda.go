@@ -0,0 +1,143 @@
+//go:build cuda
+
+package main
+
+/*
+#cgo LDFLAGS: -lcublas -lcudart
+#include <cuda_runtime.h>
+#include <cublas_v2.h>
+*/
+import "C"
+import (
+	"sync"
+	"unsafe"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// deviceMinWork is the size, in multiply-adds, below which a product stays
+// on the CPU: copying the operands to the device and back costs more than
+// gonum spends on small matrices.
+const deviceMinWork = 1 << 22
+
+// cublas holds the process-wide handle. A handle must not be used by two
+// goroutines at once, so calls take turns on it; if none can be created
+// every product falls back to gonum.
+var cublas struct {
+	sync.Mutex
+	once   sync.Once
+	handle C.cublasHandle_t
+	ok     bool
+}
+
+// deviceMul computes dst = a * b with cuBLAS and reports whether it did.
+// It declines, leaving dst untouched, for small products and on any CUDA
+// error, so the caller can run the product on the CPU instead.
+func deviceMul(dst *mat.Dense, a, b mat.Matrix) bool {
+	m, k := a.Dims()
+	br, n := b.Dims()
+	if k != br || m*k*n < deviceMinWork {
+		return false
+	}
+
+	cublas.once.Do(func() {
+		cublas.ok = C.cublasCreate(&cublas.handle) == C.CUBLAS_STATUS_SUCCESS
+	})
+	if !cublas.ok {
+		return false
+	}
+
+	ga, ta := toGeneral64(a)
+	gb, tb := toGeneral64(b)
+	out := make([]float64, m*n)
+
+	cublas.Lock()
+	ok := deviceGemm(ga, gb, out, ta, tb, m, n, k)
+	cublas.Unlock()
+	if !ok {
+		return false
+	}
+
+	if dst.IsEmpty() {
+		dst.ReuseAs(m, n)
+	} else if r, c := dst.Dims(); r != m || c != n {
+		panic(mat.ErrShape)
+	}
+	for i := 0; i < m; i++ {
+		dst.SetRow(i, out[i*n:(i+1)*n])
+	}
+	return true
+}
+
+// deviceGemm runs out = op(a) * op(b) on the device, where a and b hold the
+// stored, untransposed operands row-major. cuBLAS is column-major, so it
+// computes outᵀ = op(b)ᵀ * op(a)ᵀ, which reads the row-major buffers as they
+// are and leaves out row-major.
+func deviceGemm(a, b, out []float64, transA, transB bool, m, n, k int) bool {
+	lda, ldb := k, n
+	if transA {
+		lda = m
+	}
+	if transB {
+		ldb = k
+	}
+
+	var dA, dB, dC unsafe.Pointer
+	defer func() {
+		for _, p := range []unsafe.Pointer{dA, dB, dC} {
+			if p != nil {
+				C.cudaFree(p)
+			}
+		}
+	}()
+	for _, alloc := range []struct {
+		p    *unsafe.Pointer
+		size int
+	}{{&dA, len(a)}, {&dB, len(b)}, {&dC, len(out)}} {
+		if C.cudaMalloc(alloc.p, C.size_t(alloc.size*8)) != C.cudaSuccess {
+			return false
+		}
+	}
+
+	if C.cudaMemcpy(dA, unsafe.Pointer(&a[0]), C.size_t(len(a)*8), C.cudaMemcpyHostToDevice) != C.cudaSuccess ||
+		C.cudaMemcpy(dB, unsafe.Pointer(&b[0]), C.size_t(len(b)*8), C.cudaMemcpyHostToDevice) != C.cudaSuccess {
+		return false
+	}
+
+	alpha, beta := C.double(1), C.double(0)
+	status := C.cublasDgemm(cublas.handle, cublasOp(transB), cublasOp(transA),
+		C.int(n), C.int(m), C.int(k),
+		&alpha, (*C.double)(dB), C.int(ldb), (*C.double)(dA), C.int(lda),
+		&beta, (*C.double)(dC), C.int(n))
+	if status != C.CUBLAS_STATUS_SUCCESS {
+		return false
+	}
+
+	return C.cudaMemcpy(unsafe.Pointer(&out[0]), dC, C.size_t(len(out)*8), C.cudaMemcpyDeviceToHost) == C.cudaSuccess
+}
+
+func cublasOp(trans bool) C.cublasOperation_t {
+	if trans {
+		return C.CUBLAS_OP_T
+	}
+	return C.CUBLAS_OP_N
+}
+
+// toGeneral64 returns m's stored elements as one contiguous row-major
+// slice. Transposed views are unwrapped and reported as transposed, and a
+// contiguous *mat.Dense is used in place rather than copied.
+func toGeneral64(m mat.Matrix) ([]float64, bool) {
+	trans := false
+	if tr, ok := m.(mat.Transpose); ok {
+		m = tr.Matrix
+		trans = true
+	}
+
+	numRows, numCols := m.Dims()
+	if d, ok := m.(*mat.Dense); ok {
+		if raw := d.RawMatrix(); raw.Stride == numCols {
+			return raw.Data[:numRows*numCols], trans
+		}
+	}
+	return mat.DenseCopyOf(m).RawMatrix().Data, trans
+}
@@ -0,0 +1,10 @@
+//go:build !cuda
+
+package main
+import "gonum.org/v1/gonum/mat"
+
+// deviceMul is the GPU matrix product, available when built with the cuda
+// tag. Without it every product runs on the CPU.
+func deviceMul(dst *mat.Dense, a, b mat.Matrix) bool {
+	return false
+}
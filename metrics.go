@@ -0,0 +1,258 @@
+package main
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+)
+
+// CompareOutputs returns the mean squared difference between two prediction
+// matrices and the mean cosine similarity of their rows.
+func CompareOutputs(a, b *mat.Dense) (mse, cosine float64) {
+	numRows, numCols := a.Dims()
+	if r, c := b.Dims(); r != numRows || c != numCols {
+		panic(mat.ErrShape)
+	}
+	if numRows == 0 || numCols == 0 {
+		return 0, 1
+	}
+
+	for i := 0; i < numRows; i++ {
+		rowA, rowB := a.RawRowView(i), b.RawRowView(i)
+
+		dist := floats.Distance(rowA, rowB, 2)
+		mse += dist * dist
+
+		normA, normB := floats.Norm(rowA, 2), floats.Norm(rowB, 2)
+		switch {
+		case normA == 0 && normB == 0:
+			cosine += 1
+		case normA == 0 || normB == 0:
+			// orthogonal by convention
+		default:
+			cosine += floats.Dot(rowA, rowB) / (normA * normB)
+		}
+	}
+
+	mse /= float64(numRows * numCols)
+	cosine /= float64(numRows)
+	return mse, cosine
+}
+
+// R2Scores returns the coefficient of determination of each output column.
+// A column whose target is constant scores 1 if it is predicted exactly and
+// 0 otherwise.
+func R2Scores(pred, target *mat.Dense) []float64 {
+	numRows, numCols := target.Dims()
+	if r, c := pred.Dims(); r != numRows || c != numCols {
+		panic(mat.ErrShape)
+	}
+
+	scores := make([]float64, numCols)
+	for j := 0; j < numCols; j++ {
+		y := mat.Col(nil, j, target)
+		mean := floats.Sum(y) / float64(numRows)
+
+		var ssRes, ssTot float64
+		for i, v := range y {
+			d := v - pred.At(i, j)
+			ssRes += d * d
+			ssTot += (v - mean) * (v - mean)
+		}
+
+		switch {
+		case ssTot != 0:
+			scores[j] = 1 - ssRes/ssTot
+		case ssRes == 0:
+			scores[j] = 1
+		default:
+			scores[j] = 0
+		}
+	}
+
+	return scores
+}
+
+// R2Score is the unweighted mean of R2Scores across outputs.
+func R2Score(pred, target *mat.Dense) float64 {
+	scores := R2Scores(pred, target)
+	if len(scores) == 0 {
+		return 0
+	}
+	return floats.Sum(scores) / float64(len(scores))
+}
+
+// ROCAUC returns the area under the ROC curve of scores against binary
+// labels, matched cell by cell; a label of 0.5 or more is positive. It uses
+// the Mann–Whitney rank statistic, with tied scores sharing their average
+// rank so that each positive-negative tie counts as half. The result is NaN
+// when the labels are all of one class and the curve is undefined.
+func ROCAUC(scores, labels *mat.Dense) float64 {
+	numRows, numCols := scores.Dims()
+	if r, c := labels.Dims(); r != numRows || c != numCols {
+		panic(mat.ErrShape)
+	}
+
+	type scored struct {
+		score    float64
+		positive bool
+	}
+	cells := make([]scored, 0, numRows*numCols)
+	for i := 0; i < numRows; i++ {
+		for j := 0; j < numCols; j++ {
+			cells = append(cells, scored{scores.At(i, j), labels.At(i, j) >= 0.5})
+		}
+	}
+	sort.Slice(cells, func(a, b int) bool { return cells[a].score < cells[b].score })
+
+	var positives, negatives, rankSum float64
+	for start := 0; start < len(cells); {
+		end := start
+		for end < len(cells) && cells[end].score == cells[start].score {
+			end++
+		}
+		// ranks start+1 through end, averaged over the tie
+		rank := float64(start+1+end) / 2
+		for _, c := range cells[start:end] {
+			if c.positive {
+				positives++
+				rankSum += rank
+			} else {
+				negatives++
+			}
+		}
+		start = end
+	}
+
+	if positives == 0 || negatives == 0 {
+		return math.NaN()
+	}
+	return (rankSum - positives*(positives+1)/2) / (positives * negatives)
+}
+
+// BestThreshold sweeps the decision threshold over scores, calling a cell
+// positive when its score is at or above the threshold as Decode does, and
+// returns the threshold that maximizes metric against binary labels (0.5
+// or more is positive) with its value. metric is "f1" or "youden" (Youden's
+// J, true positive rate minus false positive rate); any other name panics.
+// Both are NaN when the metric is undefined: no positives for F1, or labels
+// all of one class for J.
+func BestThreshold(scores, labels *mat.Dense, metric string) (threshold, score float64) {
+	numRows, numCols := scores.Dims()
+	if r, c := labels.Dims(); r != numRows || c != numCols {
+		panic(mat.ErrShape)
+	}
+	if metric != "f1" && metric != "youden" {
+		panic(fmt.Sprintf("BestThreshold: unknown metric %q", metric))
+	}
+
+	type scored struct {
+		score    float64
+		positive bool
+	}
+	cells := make([]scored, 0, numRows*numCols)
+	var positives, negatives float64
+	for i := 0; i < numRows; i++ {
+		for j := 0; j < numCols; j++ {
+			c := scored{scores.At(i, j), labels.At(i, j) >= 0.5}
+			if c.positive {
+				positives++
+			} else {
+				negatives++
+			}
+			cells = append(cells, c)
+		}
+	}
+	if positives == 0 || (metric == "youden" && negatives == 0) {
+		return math.NaN(), math.NaN()
+	}
+	sort.Slice(cells, func(a, b int) bool { return cells[a].score > cells[b].score })
+
+	// lowering the threshold past each distinct score turns its cells positive
+	threshold, score = math.NaN(), math.Inf(-1)
+	var tp, fp float64
+	for start := 0; start < len(cells); {
+		end := start
+		for end < len(cells) && cells[end].score == cells[start].score {
+			if cells[end].positive {
+				tp++
+			} else {
+				fp++
+			}
+			end++
+		}
+
+		var value float64
+		if metric == "f1" {
+			value = 2 * tp / (2*tp + fp + (positives - tp))
+		} else {
+			value = tp/positives - fp/negatives
+		}
+		if value > score {
+			threshold, score = cells[start].score, value
+		}
+		start = end
+	}
+
+	return threshold, score
+}
+
+// CalibrationCurve buckets scores, predicted probabilities, into bins
+// equal-width bins over [0, 1] and returns for each bin the mean predicted
+// probability and the fraction of positive labels (0.5 or more), the two
+// axes of a reliability diagram. A well-calibrated model has them equal.
+// Scores outside [0, 1] fall into the end bins; empty bins are NaN in both.
+func CalibrationCurve(scores, labels *mat.Dense, bins int) (binMeanPredicted, binObservedFreq []float64) {
+	numRows, numCols := scores.Dims()
+	if r, c := labels.Dims(); r != numRows || c != numCols {
+		panic(mat.ErrShape)
+	}
+	if bins < 1 {
+		panic(fmt.Sprintf("CalibrationCurve: need at least 1 bin, got %d", bins))
+	}
+
+	binMeanPredicted = make([]float64, bins)
+	binObservedFreq = make([]float64, bins)
+	counts := make([]int, bins)
+	for i := 0; i < numRows; i++ {
+		for j := 0; j < numCols; j++ {
+			score := scores.At(i, j)
+			b := min(max(int(score*float64(bins)), 0), bins-1)
+			binMeanPredicted[b] += score
+			if labels.At(i, j) >= 0.5 {
+				binObservedFreq[b]++
+			}
+			counts[b]++
+		}
+	}
+
+	for b, n := range counts {
+		if n == 0 {
+			binMeanPredicted[b], binObservedFreq[b] = math.NaN(), math.NaN()
+			continue
+		}
+		binMeanPredicted[b] /= float64(n)
+		binObservedFreq[b] /= float64(n)
+	}
+
+	return binMeanPredicted, binObservedFreq
+}
+
+// accuracy is the fraction of rows whose predicted class, as defined by
+// rowClass, matches the target's.
+func accuracy(target, pred mat.Matrix) float64 {
+	numRows, _ := target.Dims()
+	if numRows == 0 {
+		return 0
+	}
+
+	correct := 0
+	for i := 0; i < numRows; i++ {
+		if rowClass(target, i) == rowClass(pred, i) {
+			correct++
+		}
+	}
+	return float64(correct) / float64(numRows)
+}
@@ -0,0 +1,265 @@
+package main
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+var npyMagic = []byte("\x93NUMPY")
+
+// ExportNPZ writes the weights and biases as a .npz archive readable with
+// numpy.load, one float64 array per parameter.
+func (nn *NeuralNet) ExportNPZ(w io.Writer) error {
+	if nn.wHidden == nil || nn.wOut == nil {
+		return fmt.Errorf("%w: the supplied weights are empty", ErrNotTrained)
+	}
+
+	zw := zip.NewWriter(w)
+	for _, p := range nn.namedParams() {
+		f, err := zw.Create(p.name + ".npy")
+		if err != nil {
+			return err
+		}
+		if err := writeNPY(f, p.m); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// ImportNPZ loads weights and biases written by ExportNPZ (or numpy.savez)
+// into the net. The array shapes must match the net's configuration. The
+// input normalization comes from the archive too: scalerOffset and
+// scalerScale, each 1×InputNeurons, or neither, which leaves the net
+// without normalization in place of any scaler it had.
+func (nn *NeuralNet) ImportNPZ(r io.Reader) error {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		return err
+	}
+
+	arrays := make(map[string]*mat.Dense)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		m, err := readNPY(rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+		arrays[strings.TrimSuffix(f.Name, ".npy")] = m
+	}
+
+	shapes := map[string][2]int{
+		"wHidden": {nn.config.InputNeurons, nn.config.HiddenNeurons},
+		"bHidden": {1, nn.config.HiddenNeurons},
+		"wOut":    {nn.config.HiddenNeurons, nn.config.OutputNeurons},
+		"bOut":    {1, nn.config.OutputNeurons},
+	}
+	if nn.hiddenActivation() == PReLU {
+		shapes["slopes"] = [2]int{1, nn.config.HiddenNeurons}
+	}
+	offset, hasOffset := arrays["scalerOffset"]
+	scale, hasScale := arrays["scalerScale"]
+	if hasOffset != hasScale {
+		return fmt.Errorf("archive has only one of scalerOffset and scalerScale")
+	}
+	if hasOffset {
+		shapes["scalerOffset"] = [2]int{1, nn.config.InputNeurons}
+		shapes["scalerScale"] = [2]int{1, nn.config.InputNeurons}
+	}
+	for name, shape := range shapes {
+		m, ok := arrays[name]
+		if !ok {
+			return fmt.Errorf("archive is missing %s", name)
+		}
+		if r, c := m.Dims(); r != shape[0] || c != shape[1] {
+			return fmt.Errorf("%w: %s has shape (%d, %d), expected (%d, %d)", ErrDimensionMismatch, name, r, c, shape[0], shape[1])
+		}
+	}
+
+	nn.wHidden = arrays["wHidden"]
+	nn.bHidden = arrays["bHidden"]
+	nn.wOut = arrays["wOut"]
+	nn.bOut = arrays["bOut"]
+	nn.slopes = nil
+	if nn.hiddenActivation() == PReLU {
+		nn.slopes = arrays["slopes"]
+	}
+	nn.resetOptimizer()
+
+	nn.scaler = nil
+	if hasOffset {
+		nn.scaler = &scaler{
+			Offset: mat.Row(nil, 0, offset),
+			Scale:  mat.Row(nil, 0, scale),
+		}
+	}
+
+	return nil
+}
+
+type namedParam struct {
+	name string
+	m    *mat.Dense
+}
+
+func (nn *NeuralNet) namedParams() []namedParam {
+	params := []namedParam{
+		{"wHidden", nn.wHidden},
+		{"bHidden", nn.bHidden},
+		{"wOut", nn.wOut},
+		{"bOut", nn.bOut},
+	}
+	if nn.slopes != nil {
+		params = append(params, namedParam{"slopes", nn.slopes})
+	}
+	if nn.scaler != nil {
+		n := len(nn.scaler.Offset)
+		params = append(params,
+			namedParam{"scalerOffset", mat.NewDense(1, n, nn.scaler.Offset)},
+			namedParam{"scalerScale", mat.NewDense(1, n, nn.scaler.Scale)},
+		)
+	}
+	return params
+}
+
+func writeNPY(w io.Writer, m *mat.Dense) error {
+	numRows, numCols := m.Dims()
+
+	header := fmt.Sprintf("{'descr': '<f8', 'fortran_order': False, 'shape': (%d, %d), }", numRows, numCols)
+	// magic, version and length prefix take 10 bytes; pad so the data is
+	// 64-byte aligned and the header ends in a newline
+	pad := 64 - (10+len(header)+1)%64
+	header += strings.Repeat(" ", pad%64) + "\n"
+
+	buf := new(bytes.Buffer)
+	buf.Write(npyMagic)
+	buf.Write([]byte{1, 0})
+	binary.Write(buf, binary.LittleEndian, uint16(len(header)))
+	buf.WriteString(header)
+
+	for i := 0; i < numRows; i++ {
+		for j := 0; j < numCols; j++ {
+			binary.Write(buf, binary.LittleEndian, m.At(i, j))
+		}
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+var (
+	npyDescr   = regexp.MustCompile(`'descr':\s*'([<>|=]?[fi]\d)'`)
+	npyFortran = regexp.MustCompile(`'fortran_order':\s*(True|False)`)
+	npyShape   = regexp.MustCompile(`'shape':\s*\(([^)]*)\)`)
+)
+
+func readNPY(r io.Reader) (*mat.Dense, error) {
+	prefix := make([]byte, 8)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(prefix[:6], npyMagic) {
+		return nil, fmt.Errorf("not a .npy file")
+	}
+
+	var headerLen int
+	switch prefix[6] {
+	case 1:
+		var n uint16
+		if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+			return nil, err
+		}
+		headerLen = int(n)
+	case 2, 3:
+		var n uint32
+		if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+			return nil, err
+		}
+		headerLen = int(n)
+	default:
+		return nil, fmt.Errorf("unsupported .npy version %d", prefix[6])
+	}
+
+	headerBytes := make([]byte, headerLen)
+	if _, err := io.ReadFull(r, headerBytes); err != nil {
+		return nil, err
+	}
+	header := string(headerBytes)
+
+	descr := npyDescr.FindStringSubmatch(header)
+	fortran := npyFortran.FindStringSubmatch(header)
+	shape := npyShape.FindStringSubmatch(header)
+	if descr == nil || fortran == nil || shape == nil {
+		return nil, fmt.Errorf("malformed .npy header %q", header)
+	}
+
+	var dims []int
+	for _, d := range strings.Split(shape[1], ",") {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+		n, err := strconv.Atoi(d)
+		if err != nil {
+			return nil, fmt.Errorf("malformed .npy shape %q", shape[1])
+		}
+		dims = append(dims, n)
+	}
+
+	numRows, numCols := 1, 1
+	switch len(dims) {
+	case 1:
+		numCols = dims[0]
+	case 2:
+		numRows, numCols = dims[0], dims[1]
+	default:
+		return nil, fmt.Errorf("only 1-D and 2-D arrays are supported, got shape %q", shape[1])
+	}
+	if numRows == 0 || numCols == 0 {
+		return nil, fmt.Errorf("empty array")
+	}
+
+	var order binary.ByteOrder = binary.LittleEndian
+	if strings.HasPrefix(descr[1], ">") {
+		order = binary.BigEndian
+	}
+
+	data := make([]float64, numRows*numCols)
+	switch strings.TrimLeft(descr[1], "<>|=") {
+	case "f8":
+		if err := binary.Read(r, order, data); err != nil {
+			return nil, err
+		}
+	case "f4":
+		raw := make([]float32, len(data))
+		if err := binary.Read(r, order, raw); err != nil {
+			return nil, err
+		}
+		for i, v := range raw {
+			data[i] = float64(v)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported dtype %q", descr[1])
+	}
+
+	if fortran[1] == "True" {
+		m := mat.NewDense(numCols, numRows, data)
+		return mat.DenseCopyOf(m.T()), nil
+	}
+	return mat.NewDense(numRows, numCols, data), nil
+}
@@ -0,0 +1,35 @@
+package main
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+func TestImportNPZScaler(t *testing.T) {
+	src := trainedXOR(t, NetConfig{Normalization: ZScoreNormalization})
+	x, _ := xorData()
+	want, _ := src.Predict(x)
+
+	var buf bytes.Buffer
+	if err := src.ExportNPZ(&buf); err != nil {
+		t.Fatalf("ExportNPZ: %v", err)
+	}
+	dst := NewNet(src.config)
+	if err := dst.ImportNPZ(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("ImportNPZ: %v", err)
+	}
+	if got, _ := dst.Predict(x); !mat.Equal(got, want) {
+		t.Errorf("imported net predicts %v, want %v", mat.Formatted(got), mat.Formatted(want))
+	}
+
+	src.scaler = &scaler{Offset: []float64{0, 0, 0}, Scale: []float64{1, 1, 1}}
+	buf.Reset()
+	if err := src.ExportNPZ(&buf); err != nil {
+		t.Fatalf("ExportNPZ: %v", err)
+	}
+	if err := dst.ImportNPZ(bytes.NewReader(buf.Bytes())); !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("ImportNPZ of a 3-column scaler into a 2-input net: err = %v, want %v", err, ErrDimensionMismatch)
+	}
+}
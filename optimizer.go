@@ -0,0 +1,124 @@
+package main
+import (
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+type Optimizer int
+
+const (
+	SGD      Optimizer = iota // plain gradient descent
+	Momentum                  // gradient descent with a decaying velocity
+	Adam                      // per-parameter steps from bias-corrected moment estimates
+)
+
+type OptimizerConfig struct {
+	Kind     Optimizer
+	Momentum float64 // velocity decay for Momentum, 0 means 0.9
+	// Nesterov makes Momentum take the gradient at the look-ahead position
+	// w + Momentum*v rather than at w
+	Nesterov bool
+	// Adam's moment decay rates and denominator guard; zero values fall back
+	// to the paper's 0.9, 0.999 and 1e-8
+	Beta1   float64
+	Beta2   float64
+	Epsilon float64
+}
+
+func (c OptimizerConfig) momentum() float64 {
+	return orDefault(c.Momentum, 0.9)
+}
+
+func (c OptimizerConfig) adamParams() (beta1, beta2, epsilon float64) {
+	return orDefault(c.Beta1, 0.9), orDefault(c.Beta2, 0.999), orDefault(c.Epsilon, 1e-8)
+}
+
+func orDefault(v, fallback float64) float64 {
+	if v == 0 {
+		return fallback
+	}
+	return v
+}
+
+// resetOptimizer drops the optimizer state, for when the weights it was
+// built up on are replaced.
+func (nn *NeuralNet) resetOptimizer() {
+	nn.velocity = nil
+	nn.secondMoment = nil
+	nn.adamSteps = 0
+}
+
+// evalPoint returns the parameters the gradient of the next step is taken
+// at: params themselves, or their Nesterov look-ahead.
+func (nn *NeuralNet) evalPoint(params []*mat.Dense) []*mat.Dense {
+	opt := nn.config.Optimizer
+	if opt.Kind != Momentum || !opt.Nesterov || nn.velocity == nil {
+		return params
+	}
+
+	ahead := make([]*mat.Dense, len(params))
+	for i, w := range params {
+		ahead[i] = new(mat.Dense)
+		ahead[i].Scale(opt.momentum(), nn.velocity[i])
+		ahead[i].Add(ahead[i], w)
+	}
+	return ahead
+}
+
+// update applies one optimizer step to params given their gradients and
+// per-parameter learning rates, leaving the gradients as they are.
+func (nn *NeuralNet) update(params, grads []*mat.Dense, lrs []float64) {
+	opt := nn.config.Optimizer
+	switch opt.Kind {
+	case Momentum:
+		if nn.velocity == nil {
+			nn.velocity = zerosLike(params)
+		}
+		momentum := opt.momentum()
+		for i, w := range params {
+			v, g, lr := nn.velocity[i], grads[i], lrs[i]
+			v.Apply(func(r, c int, vv float64) float64 {
+				return momentum*vv - lr*g.At(r, c)
+			}, v)
+			w.Add(w, v)
+		}
+	case Adam:
+		if nn.velocity == nil || nn.secondMoment == nil {
+			nn.velocity, nn.secondMoment, nn.adamSteps = zerosLike(params), zerosLike(params), 0
+		}
+		beta1, beta2, epsilon := opt.adamParams()
+		nn.adamSteps++
+		correct1 := 1 - math.Pow(beta1, float64(nn.adamSteps))
+		correct2 := 1 - math.Pow(beta2, float64(nn.adamSteps))
+		for i, w := range params {
+			m, v, g, lr := nn.velocity[i], nn.secondMoment[i], grads[i], lrs[i]
+			m.Apply(func(r, c int, mv float64) float64 {
+				return beta1*mv + (1-beta1)*g.At(r, c)
+			}, m)
+			v.Apply(func(r, c int, vv float64) float64 {
+				gv := g.At(r, c)
+				return beta2*vv + (1-beta2)*gv*gv
+			}, v)
+			w.Apply(func(r, c int, wv float64) float64 {
+				return wv - lr*(m.At(r, c)/correct1)/(math.Sqrt(v.At(r, c)/correct2)+epsilon)
+			}, w)
+		}
+	default:
+		for i, w := range params {
+			g, lr := grads[i], lrs[i]
+			w.Apply(func(r, c int, wv float64) float64 {
+				return wv - lr*g.At(r, c)
+			}, w)
+		}
+	}
+}
+
+func zerosLike(params []*mat.Dense) []*mat.Dense {
+	zeros := make([]*mat.Dense, len(params))
+	for i, w := range params {
+		r, c := w.Dims()
+		zeros[i] = mat.NewDense(r, c, nil)
+	}
+	return zeros
+}
@@ -0,0 +1,194 @@
+package main
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+type savedNet struct {
+	Config  NetConfig
+	WHidden *mat.Dense
+	BHidden *mat.Dense
+	WOut    *mat.Dense
+	BOut    *mat.Dense
+	Slopes  *mat.Dense
+	Scaler  *scaler
+	Epoch   int
+	// training state for ContinueTraining; absent from files written
+	// before it was saved, which resume with a fresh optimizer
+	Velocity     []*mat.Dense
+	SecondMoment []*mat.Dense
+	AdamSteps    int
+	BatchSize    int
+	LR           float64
+	RNG          *savedRand
+}
+
+// savedRand is the position of the training stream: its seed and the
+// number of draws taken since.
+type savedRand struct {
+	Seed  int64
+	Draws uint64
+}
+
+// Save writes the configuration, weights and training progress of the net
+// in gob format, including the optimizer state, current learning rate and
+// training stream position ContinueTraining resumes from.
+func (nn *NeuralNet) Save(w io.Writer) error {
+	if nn.wHidden == nil || nn.wOut == nil {
+		return fmt.Errorf("%w: the supplied weights are empty", ErrNotTrained)
+	}
+
+	// a writer or sampler is process state, and gob cannot encode an
+	// arbitrary one
+	conf := nn.config
+	conf.ProgressWriter = nil
+	conf.Sampler = nil
+
+	saved := savedNet{
+		Config:       conf,
+		WHidden:      nn.wHidden,
+		BHidden:      nn.bHidden,
+		WOut:         nn.wOut,
+		BOut:         nn.bOut,
+		Slopes:       nn.slopes,
+		Scaler:       nn.scaler,
+		Epoch:        nn.epoch,
+		Velocity:     nn.velocity,
+		SecondMoment: nn.secondMoment,
+		AdamSteps:    nn.adamSteps,
+		BatchSize:    nn.batchSize,
+		LR:           nn.lr,
+	}
+	if c := nn.rngSource; c != nil {
+		saved.RNG = &savedRand{Seed: c.seed, Draws: c.draws}
+	}
+	return gob.NewEncoder(w).Encode(saved)
+}
+
+// SaveFile writes the net to path, replacing any previous file only once the
+// new one is complete.
+func (nn *NeuralNet) SaveFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := nn.Save(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func LoadNet(r io.Reader) (*NeuralNet, error) {
+	var saved savedNet
+	if err := gob.NewDecoder(r).Decode(&saved); err != nil {
+		return nil, err
+	}
+
+	nn := NewNet(saved.Config)
+	nn.wHidden = saved.WHidden
+	nn.bHidden = saved.BHidden
+	nn.wOut = saved.WOut
+	nn.bOut = saved.BOut
+	nn.slopes = saved.Slopes
+	nn.scaler = saved.Scaler
+	nn.epoch = saved.Epoch
+	nn.velocity = saved.Velocity
+	nn.secondMoment = saved.SecondMoment
+	nn.adamSteps = saved.AdamSteps
+	nn.batchSize = saved.BatchSize
+	nn.lr = saved.LR
+	if saved.RNG != nil {
+		nn.seedTrainRand(saved.RNG.Seed)
+		nn.rngSource.skip(saved.RNG.Draws)
+	}
+
+	return nn, nil
+}
+
+func LoadNetFile(path string) (*NeuralNet, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadNet(f)
+}
+
+// ConfigHash fingerprints the parts of the configuration that decide what
+// the net computes from an input row: layer widths, activations, output
+// quantiles and whether inputs are normalized. Training-only settings such
+// as the learning rate are left out, so a retrained model of the same shape
+// hashes the same. Compare it against the value your code expects before
+// serving a loaded net.
+func (nn *NeuralNet) ConfigHash() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "inputs=%d hidden=%d outputs=%d\n", nn.config.InputNeurons, nn.config.HiddenNeurons, nn.config.OutputNeurons)
+	fmt.Fprintf(h, "hidden_activation=%d output_activation=%d\n", nn.hiddenActivation(), nn.config.OutputActivation)
+	fmt.Fprintf(h, "normalization=%d quantiles=%v\n", nn.config.Normalization, nn.config.OutputQuantiles)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// AverageWeights returns a net whose weights and biases are the element-wise
+// mean of those of nets, as in stochastic weight averaging over checkpoints
+// of one run. The nets must share a ConfigHash and input normalization; the
+// result carries the configuration, scaler and epoch of the last net.
+func AverageWeights(nets []*NeuralNet) (*NeuralNet, error) {
+	if len(nets) == 0 {
+		return nil, fmt.Errorf("%w: no nets to average", ErrEmptyData)
+	}
+	last := nets[len(nets)-1]
+	for i, nn := range nets {
+		if nn.wHidden == nil || nn.wOut == nil {
+			return nil, fmt.Errorf("net %d: %w: the supplied weights are empty", i, ErrNotTrained)
+		}
+		if nn.ConfigHash() != last.ConfigHash() {
+			return nil, fmt.Errorf("%w: net %d has a different topology from net %d", ErrDimensionMismatch, i, len(nets)-1)
+		}
+		if !sameScaler(nn.scaler, last.scaler) {
+			return nil, fmt.Errorf("net %d normalizes its inputs differently from net %d", i, len(nets)-1)
+		}
+	}
+
+	avg := NewNet(last.config)
+	avg.scaler = last.scaler
+	avg.epoch = last.epoch
+	sum := func(field func(*NeuralNet) *mat.Dense) *mat.Dense {
+		m := mat.DenseCopyOf(field(nets[0]))
+		for _, nn := range nets[1:] {
+			m.Add(m, field(nn))
+		}
+		m.Scale(1/float64(len(nets)), m)
+		return m
+	}
+	avg.wHidden = sum(func(nn *NeuralNet) *mat.Dense { return nn.wHidden })
+	avg.bHidden = sum(func(nn *NeuralNet) *mat.Dense { return nn.bHidden })
+	avg.wOut = sum(func(nn *NeuralNet) *mat.Dense { return nn.wOut })
+	avg.bOut = sum(func(nn *NeuralNet) *mat.Dense { return nn.bOut })
+	if last.slopes != nil {
+		avg.slopes = sum(func(nn *NeuralNet) *mat.Dense { return nn.slopes })
+	}
+
+	return avg, nil
+}
+
+func sameScaler(a, b *scaler) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return slices.Equal(a.Offset, b.Offset) && slices.Equal(a.Scale, b.Scale)
+}
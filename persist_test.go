@@ -0,0 +1,89 @@
+package main
+import (
+	"path/filepath"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// A run resumed from its halfway checkpoint ends on the same weights as the
+// uninterrupted run.
+func TestCheckpointResume(t *testing.T) {
+	x := mat.NewDense(8, 2, []float64{0, 0, 0, 1, 1, 0, 1, 1, 0.1, 0.1, 0.1, 0.9, 0.9, 0.1, 0.9, 0.9})
+	y := mat.NewDense(8, 1, []float64{0, 1, 1, 0, 0, 1, 1, 0})
+	for _, opt := range []OptimizerConfig{{Kind: Momentum, Nesterov: true}, {Kind: Adam}} {
+		path := filepath.Join(t.TempDir(), "net.gob")
+		conf := NetConfig{
+			InputNeurons:    2,
+			HiddenNeurons:   4,
+			OutputNeurons:   1,
+			NumEpochs:       20,
+			LearningRate:    0.5,
+			Seed:            7,
+			Optimizer:       opt,
+			BatchSize:       3,
+			ValidationSplit: 0.25,
+			CheckpointEvery: 10,
+			CheckpointPath:  path,
+		}
+		full := NewNet(conf)
+		if err := full.Train(x, y); err != nil {
+			t.Fatalf("Train: %v", err)
+		}
+
+		// the file now holds epoch 20, so stop a second run at 10
+		conf.NumEpochs = 10
+		if err := NewNet(conf).Train(x, y); err != nil {
+			t.Fatalf("Train: %v", err)
+		}
+		resumed, err := LoadNetFile(path)
+		if err != nil {
+			t.Fatalf("LoadNetFile: %v", err)
+		}
+		resumed.config.CheckpointEvery = 0
+		if err := resumed.ContinueTraining(x, y); err != nil {
+			t.Fatalf("ContinueTraining: %v", err)
+		}
+
+		if resumed.Epoch() != full.Epoch() {
+			t.Errorf("optimizer %v: resumed at epoch %d, want %d", opt.Kind, resumed.Epoch(), full.Epoch())
+		}
+		for _, p := range []struct {
+			name      string
+			got, want *mat.Dense
+		}{
+			{"wHidden", resumed.wHidden, full.wHidden},
+			{"bHidden", resumed.bHidden, full.bHidden},
+			{"wOut", resumed.wOut, full.wOut},
+			{"bOut", resumed.bOut, full.bOut},
+		} {
+			if !mat.EqualApprox(p.got, p.want, 1e-12) {
+				t.Errorf("optimizer %v: resumed %s = %v, want %v", opt.Kind, p.name, mat.Formatted(p.got), mat.Formatted(p.want))
+			}
+		}
+	}
+}
+
+func TestCheckpointNeedsPath(t *testing.T) {
+	x, y := xorData()
+	nn := NewNet(NetConfig{InputNeurons: 2, HiddenNeurons: 2, OutputNeurons: 1, NumEpochs: 10, LearningRate: 0.5, Seed: 1, CheckpointEvery: 5})
+	if err := nn.Train(x, y); err == nil {
+		t.Fatal("Train accepted CheckpointEvery without a CheckpointPath")
+	}
+	if nn.Epoch() != 0 {
+		t.Errorf("Train ran %d epochs before rejecting the configuration", nn.Epoch())
+	}
+}
+
+func TestContinueTrainingResetsBest(t *testing.T) {
+	nn := trainedXOR(t, NetConfig{NumEpochs: 20})
+	// make every later epoch worse than the best of Train
+	nn.wOut.Scale(100, nn.wOut)
+	nn.config.NumEpochs = 2
+	if err := nn.ContinueTraining(xorData()); err != nil {
+		t.Fatalf("ContinueTraining: %v", err)
+	}
+	if best := nn.BestWeights(); best == nil || best.Epoch() <= 20 {
+		t.Errorf("BestWeights after ContinueTraining is from before the call")
+	}
+}
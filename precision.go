@@ -0,0 +1,67 @@
+package main
+import (
+	"gonum.org/v1/gonum/blas"
+	"gonum.org/v1/gonum/blas/blas32"
+	"gonum.org/v1/gonum/mat"
+)
+
+// mulFloat32 computes dst = a * b with float32 operands and accumulation,
+// widening the result back to float64. dst follows the same reuse rules as
+// mat.Dense.Mul.
+func mulFloat32(dst *mat.Dense, a, b mat.Matrix) {
+	ga, ta := toGeneral32(a)
+	gb, tb := toGeneral32(b)
+
+	ar, ac := a.Dims()
+	br, bc := b.Dims()
+	if ac != br {
+		panic(mat.ErrShape)
+	}
+
+	gc := blas32.General{Rows: ar, Cols: bc, Stride: bc, Data: make([]float32, ar*bc)}
+	blas32.Gemm(ta, tb, 1, ga, gb, 0, gc)
+
+	if dst.IsEmpty() {
+		dst.ReuseAs(ar, bc)
+	} else if r, c := dst.Dims(); r != ar || c != bc {
+		panic(mat.ErrShape)
+	}
+
+	raw := dst.RawMatrix()
+	for i := 0; i < ar; i++ {
+		row := raw.Data[i*raw.Stride : i*raw.Stride+bc]
+		for j := range row {
+			row[j] = float64(gc.Data[i*bc+j])
+		}
+	}
+}
+
+// toGeneral32 narrows m to float32. Transposed views are unwrapped and
+// reported as blas.Trans so Gemm reads the original layout.
+func toGeneral32(m mat.Matrix) (blas32.General, blas.Transpose) {
+	t := blas.NoTrans
+	if tr, ok := m.(mat.Transpose); ok {
+		m = tr.Matrix
+		t = blas.Trans
+	}
+
+	numRows, numCols := m.Dims()
+	g := blas32.General{Rows: numRows, Cols: numCols, Stride: numCols, Data: make([]float32, numRows*numCols)}
+
+	if d, ok := m.(*mat.Dense); ok {
+		raw := d.RawMatrix()
+		for i := 0; i < numRows; i++ {
+			for j, v := range raw.Data[i*raw.Stride : i*raw.Stride+numCols] {
+				g.Data[i*numCols+j] = float32(v)
+			}
+		}
+		return g, t
+	}
+
+	for i := 0; i < numRows; i++ {
+		for j := 0; j < numCols; j++ {
+			g.Data[i*numCols+j] = float32(m.At(i, j))
+		}
+	}
+	return g, t
+}
@@ -0,0 +1,19 @@
+package main
+import (
+	"math"
+	"testing"
+)
+
+func TestMixedPrecisionConvergence(t *testing.T) {
+	final := func(mixed bool) float64 {
+		nn := trainedXOR(t, NetConfig{HiddenNeurons: 8, NumEpochs: 300, LearningRate: 2, MixedPrecision: mixed})
+		epochs := nn.TrainingReport().Epochs
+		return epochs[len(epochs)-1].TrainLoss
+	}
+
+	full, mixed := final(false), final(true)
+	if d := math.Abs(full - mixed); d > 1e-6 {
+		t.Errorf("final loss %v with MixedPrecision, %v without: differ by %v", mixed, full, d)
+	}
+	t.Logf("final loss %.7f with MixedPrecision, %.7f without", mixed, full)
+}
@@ -0,0 +1,107 @@
+package main
+import (
+	"fmt"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// predictScratch holds the forward-pass intermediates for up to rows rows.
+type predictScratch struct {
+	rows   int
+	scaled *mat.Dense
+	hidden *mat.Dense
+}
+
+// Prepare allocates the forward-pass intermediates PredictInto needs for
+// batches of up to batchSize rows, so that no call has to. It must be called
+// again after the net's shape changes.
+func (nn *NeuralNet) Prepare(batchSize int) {
+	if batchSize <= 0 {
+		return
+	}
+
+	nn.scratchMu.Lock()
+	defer nn.scratchMu.Unlock()
+
+	nn.scratch = &predictScratch{
+		rows:   batchSize,
+		scaled: mat.NewDense(batchSize, nn.config.InputNeurons, nil),
+		hidden: mat.NewDense(batchSize, nn.config.HiddenNeurons, nil),
+	}
+}
+
+// PredictInto is Predict writing into dst, which must have one row per row
+// of x and OutputNeurons columns. Batches no larger than the one given to
+// Prepare reuse its buffers instead of allocating intermediates. Like
+// Predict it is safe for concurrent use: a call that finds the buffers busy
+// allocates its own instead of waiting.
+func (nn *NeuralNet) PredictInto(dst, x *mat.Dense) error {
+	if nn.wHidden == nil || nn.wOut == nil {
+		return fmt.Errorf("%w: the supplied weights are empty", ErrNotTrained)
+	}
+	if nn.bHidden == nil || nn.bOut == nil {
+		return fmt.Errorf("%w: the supplied biases are empty", ErrNotTrained)
+	}
+	numRows, numCols := x.Dims()
+	if numCols != nn.config.InputNeurons {
+		return fmt.Errorf("%w: input has %d columns but InputNeurons is %d", ErrDimensionMismatch, numCols, nn.config.InputNeurons)
+	}
+	if r, c := dst.Dims(); r != numRows || c != nn.config.OutputNeurons {
+		return fmt.Errorf("%w: dst is %dx%d but the prediction is %dx%d", ErrDimensionMismatch, r, c, numRows, nn.config.OutputNeurons)
+	}
+
+	// the buffers belong to whichever call holds the lock; anyone else
+	// allocates its own rather than queueing behind it
+	var in mat.Matrix = x
+	var hidden *mat.Dense
+	if nn.scratchMu.TryLock() {
+		defer nn.scratchMu.Unlock()
+		if s := nn.scratch; s != nil && numRows <= s.rows {
+			hidden = s.hidden.Slice(0, numRows, 0, nn.config.HiddenNeurons).(*mat.Dense)
+			if nn.scaler != nil {
+				scaled := s.scaled.Slice(0, numRows, 0, numCols).(*mat.Dense)
+				nn.scaler.transformInto(scaled, x)
+				in = scaled
+			}
+		}
+	}
+	if hidden == nil {
+		hidden = new(mat.Dense)
+		if nn.scaler != nil {
+			in = nn.scaler.transform(x)
+		}
+	}
+
+	nn.forwardInto(dst, hidden, in)
+	return nil
+}
+
+// PredictChunked is Predict run chunkRows rows at a time, so the forward
+// pass never holds intermediates for more than one chunk; only the output
+// is allocated in full. Each output row depends on its input row alone, so
+// the result is identical to Predict's, except that with the cuda build tag
+// chunks below the GPU size threshold multiply on the CPU and may differ
+// in the last bits.
+func (nn *NeuralNet) PredictChunked(x *mat.Dense, chunkRows int) (*mat.Dense, error) {
+	if chunkRows <= 0 {
+		return nil, fmt.Errorf("chunk size must be positive, got %d", chunkRows)
+	}
+	if nn.wHidden == nil || nn.wOut == nil {
+		return nil, fmt.Errorf("%w: the supplied weights are empty", ErrNotTrained)
+	}
+	if x.IsEmpty() {
+		return nil, fmt.Errorf("%w: no rows to predict", ErrEmptyData)
+	}
+
+	numRows, numCols := x.Dims()
+	output := mat.NewDense(numRows, nn.config.OutputNeurons, nil)
+	for start := 0; start < numRows; start += chunkRows {
+		end := min(start+chunkRows, numRows)
+		dst := output.Slice(start, end, 0, nn.config.OutputNeurons).(*mat.Dense)
+		if err := nn.PredictInto(dst, x.Slice(start, end, 0, numCols).(*mat.Dense)); err != nil {
+			return nil, err
+		}
+	}
+
+	return output, nil
+}
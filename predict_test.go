@@ -0,0 +1,151 @@
+package main
+import (
+	"sync"
+	"testing"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// xorData is the four-row XOR problem.
+func xorData() (x, y *mat.Dense) {
+	x = mat.NewDense(4, 2, []float64{0, 0, 0, 1, 1, 0, 1, 1})
+	y = mat.NewDense(4, 1, []float64{0, 1, 1, 0})
+	return x, y
+}
+
+func trainedXOR(t testing.TB, conf NetConfig) *NeuralNet {
+	t.Helper()
+	conf.InputNeurons, conf.OutputNeurons = 2, 1
+	if conf.HiddenNeurons == 0 {
+		conf.HiddenNeurons = 4
+	}
+	if conf.NumEpochs == 0 {
+		conf.NumEpochs = 200
+	}
+	if conf.LearningRate == 0 {
+		conf.LearningRate = 0.5
+	}
+	if conf.Seed == 0 {
+		conf.Seed = 7
+	}
+
+	nn := NewNet(conf)
+	x, y := xorData()
+	if err := nn.Train(x, y); err != nil {
+		t.Fatalf("Train: %v", err)
+	}
+	return nn
+}
+
+// Run with -race: Predict, PredictInto and PredictChunked share the net,
+// and PredictInto the scratch buffers from Prepare.
+func TestPredictConcurrent(t *testing.T) {
+	nn := trainedXOR(t, NetConfig{Normalization: ZScoreNormalization})
+	x, _ := xorData()
+	want, err := nn.Predict(x)
+	if err != nil {
+		t.Fatalf("Predict: %v", err)
+	}
+	nn.Prepare(4)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 3*8)
+	check := func(name string, got *mat.Dense) {
+		if !mat.Equal(got, want) {
+			t.Errorf("%s = %v, want %v", name, mat.Formatted(got), mat.Formatted(want))
+		}
+	}
+	for g := 0; g < 8; g++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				got, err := nn.Predict(x)
+				if err != nil {
+					errs <- err
+					return
+				}
+				check("Predict", got)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			dst := mat.NewDense(4, 1, nil)
+			for i := 0; i < 50; i++ {
+				if err := nn.PredictInto(dst, x); err != nil {
+					errs <- err
+					return
+				}
+				check("PredictInto", dst)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				got, err := nn.PredictChunked(x, 3)
+				if err != nil {
+					errs <- err
+					return
+				}
+				check("PredictChunked", got)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestPredictIntoLargerThanScratch(t *testing.T) {
+	nn := trainedXOR(t, NetConfig{Normalization: ZScoreNormalization})
+	x, _ := xorData()
+	want, _ := nn.Predict(x)
+
+	nn.Prepare(2)
+	dst := mat.NewDense(4, 1, nil)
+	if err := nn.PredictInto(dst, x); err != nil {
+		t.Fatalf("PredictInto: %v", err)
+	}
+	if !mat.Equal(dst, want) {
+		t.Errorf("PredictInto = %v, want %v", mat.Formatted(dst), mat.Formatted(want))
+	}
+}
+
+func TestTransposedWOutMatches(t *testing.T) {
+	x, _ := xorData()
+	for _, opt := range []OptimizerConfig{{}, {Kind: Momentum, Nesterov: true}, {Kind: Adam}} {
+		plain := trainedXOR(t, NetConfig{Optimizer: opt})
+		transposed := trainedXOR(t, NetConfig{Optimizer: opt, TransposedWOut: true})
+
+		want, _ := plain.Predict(x)
+		got, _ := transposed.Predict(x)
+		if !mat.EqualApprox(got, want, 1e-12) {
+			t.Errorf("optimizer %v: TransposedWOut predicts %v, want %v", opt.Kind, mat.Formatted(got), mat.Formatted(want))
+		}
+		if c := transposed.wOutT.Load(); c == nil || !mat.Equal(c.t, transposed.wOut.T()) {
+			t.Errorf("optimizer %v: transposed wOut is out of step with wOut", opt.Kind)
+		}
+	}
+}
+
+// The pair measures TransposedWOut on a tall batch with few outputs.
+func benchmarkPredict(b *testing.B, transposed bool) {
+	const rows, inputs, hidden, outputs = 4096, 64, 256, 2
+	nn := NewNet(NetConfig{InputNeurons: inputs, HiddenNeurons: hidden, OutputNeurons: outputs, TransposedWOut: transposed})
+	nn.wHidden, nn.bHidden = initLayer(1, inputs, hidden)
+	nn.wOut, nn.bOut = initLayer(2, hidden, outputs)
+	x, _ := initLayer(3, rows, inputs)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := nn.Predict(x); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPredict(b *testing.B) { benchmarkPredict(b, false) }
+
+func BenchmarkPredictTransposedWOut(b *testing.B) { benchmarkPredict(b, true) }
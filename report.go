@@ -0,0 +1,150 @@
+package main
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+type EpochReport struct {
+	Epoch        int
+	TrainLoss    float64
+	ValLoss      float64 // NaN without a validation split
+	LearningRate float64
+	BatchSize    int // rows per batch, as grown by AdaptiveBatchMax; 0 is the full set
+	// Accuracy is the mean per-batch accuracy over the epoch and
+	// SmoothedAccuracy its bias-corrected moving average across batches
+	Accuracy         float64
+	SmoothedAccuracy float64
+	// Weights summarizes each parameter matrix at the end of the epoch,
+	// keyed wHidden, bHidden, wOut, bOut and, for PReLU, slopes as in
+	// ExportNPZ
+	Weights map[string]WeightStats
+	// UpdateRatios is, per parameter matrix, the mean over the epoch's
+	// steps of ||update|| / ||weights|| (Frobenius norms), leaving out
+	// steps where the weights were all zero. Around 1e-3 is healthy; far
+	// above or below suggests the learning rate is off
+	UpdateRatios map[string]float64
+}
+
+// writeProgress prints one ProgressWriter line for e, out of lastEpoch.
+// Write errors are ignored: progress output must not stop training.
+func writeProgress(w io.Writer, e EpochReport, lastEpoch int, eta time.Duration) {
+	line := fmt.Sprintf("epoch %d/%d  loss %.6g", e.Epoch, lastEpoch, e.TrainLoss)
+	if !math.IsNaN(e.ValLoss) {
+		line += fmt.Sprintf("  val %.6g", e.ValLoss)
+	}
+	fmt.Fprintf(w, "%s  eta %s\n", line, eta.Round(time.Second))
+}
+
+// WeightStats describes the distribution of the entries of one matrix. A
+// standard deviation collapsing towards zero, or a growing max, flags a
+// layer that is dying or exploding.
+type WeightStats struct {
+	Min, Max  float64
+	Mean, Std float64
+}
+
+func summarizeWeights(params []namedParam) map[string]WeightStats {
+	summary := make(map[string]WeightStats, len(params))
+	for _, p := range params {
+		data := p.m.RawMatrix().Data
+		mean, variance := stat.PopMeanVariance(data, nil)
+		summary[p.name] = WeightStats{
+			Min:  floats.Min(data),
+			Max:  floats.Max(data),
+			Mean: mean,
+			Std:  math.Sqrt(variance),
+		}
+	}
+	return summary
+}
+
+// TrainingReport records per-epoch progress of the most recent Train call,
+// extended by any ContinueTraining calls after it.
+type TrainingReport struct {
+	Epochs       []EpochReport
+	StoppedEarly bool
+	BestEpoch    int // epoch with the lowest validation loss, or training loss without a split
+}
+
+// Report evaluates the net on a held-out test set and summarizes it as
+// text: the training loss, then either accuracy with per-class precision
+// and recall when every output is Binary, Probability or Categorical, or
+// MSE and R² per output otherwise. Errors are on the encoded scale.
+func (nn *NeuralNet) Report(ni *NeuralInterface, test []TrainingDatum) (string, error) {
+	x, y, err := ni.EncodeBatch(test)
+	if err != nil {
+		return "", err
+	}
+	pred, err := nn.Predict(x)
+	if err != nil {
+		return "", err
+	}
+
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "rows: %d\n", len(test))
+	fmt.Fprintf(b, "loss: %.6g\n", nn.loss(y, pred))
+
+	classification := true
+	for _, def := range ni.OutputSchema {
+		classification = classification && (def.Type == Binary || def.Type == Probability || def.Type == Categorical)
+	}
+
+	tw := tabwriter.NewWriter(b, 0, 4, 2, ' ', 0)
+	if classification {
+		fmt.Fprintf(b, "accuracy: %.4f\n", accuracy(y, pred))
+
+		// a single output is a yes/no decision, several are one class each
+		names := ni.OutputColumns()
+		if len(names) == 1 {
+			names = []string{"not " + names[0], names[0]}
+		}
+		fmt.Fprintln(tw, "class\tprecision\trecall\tsupport")
+		for c, name := range names {
+			var tp, fp, fn int
+			for i := range test {
+				want, got := rowClass(y, i) == c, rowClass(pred, i) == c
+				switch {
+				case want && got:
+					tp++
+				case got:
+					fp++
+				case want:
+					fn++
+				}
+			}
+			fmt.Fprintf(tw, "%s\t%.4f\t%.4f\t%d\n", name, ratio(tp, tp+fp), ratio(tp, tp+fn), tp+fn)
+		}
+	} else {
+		mse, _ := CompareOutputs(pred, y)
+		fmt.Fprintf(b, "mse: %.6g\n", mse)
+		fmt.Fprintf(b, "r2: %.4f\n", R2Score(pred, y))
+
+		fmt.Fprintln(tw, "output\tmse\tr2")
+		scores := R2Scores(pred, y)
+		for j, name := range ni.OutputColumns() {
+			colMSE, _ := CompareOutputs(mat.DenseCopyOf(pred.ColView(j)), mat.DenseCopyOf(y.ColView(j)))
+			fmt.Fprintf(tw, "%s\t%.6g\t%.4f\n", name, colMSE, scores[j])
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// ratio is num/den, or 0 for an empty denominator.
+func ratio(num, den int) float64 {
+	if den == 0 {
+		return 0
+	}
+	return float64(num) / float64(den)
+}
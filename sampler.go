@@ -0,0 +1,39 @@
+package main
+import (
+	"math/rand"
+)
+
+// Sampler chooses the batches of each training epoch, in place of the
+// default shuffle-and-cut of NetConfig.BatchSize.
+type Sampler interface {
+	// Batches returns the row indices of each batch of the given epoch,
+	// out of the n rows of the epoch's training data, in the order they are
+	// trained on. Rows may repeat or be left out; a nil batch stands for
+	// all n rows. randGen is the training stream, so samplers drawing from
+	// it follow NetConfig.Seed.
+	Batches(epoch, n int, randGen *rand.Rand) [][]int
+}
+
+// UniformSampler is the default Sampler: each epoch shuffles the rows once
+// and cuts the order into consecutive batches of at most BatchSize rows, 0
+// meaning the full set.
+type UniformSampler struct {
+	BatchSize int
+}
+
+func (s UniformSampler) Batches(_, n int, randGen *rand.Rand) [][]int {
+	return batchRows(n, s.BatchSize, randGen)
+}
+
+func (nn *NeuralNet) sampler() Sampler {
+	if nn.config.Sampler != nil {
+		return nn.config.Sampler
+	}
+	return UniformSampler{BatchSize: nn.currentBatchSize()}
+}
+
+// currentBatchSize is BatchSize, or the size AdaptiveBatchMax has grown it
+// to.
+func (nn *NeuralNet) currentBatchSize() int {
+	return max(nn.config.BatchSize, nn.batchSize)
+}
@@ -0,0 +1,97 @@
+package main
+import (
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+type Normalization int
+
+const (
+	NoNormalization     Normalization = iota
+	MinMaxNormalization               // (v - min) / (max - min)
+	ZScoreNormalization               // (v - mean) / std
+)
+
+// scaler holds per-column statistics fitted on the training inputs so that
+// Predict applies exactly the transform Train saw.
+type scaler struct {
+	Offset []float64
+	Scale  []float64
+}
+
+func fitScaler(kind Normalization, x *mat.Dense) *scaler {
+	numRows, numCols := x.Dims()
+	s := &scaler{
+		Offset: make([]float64, numCols),
+		Scale:  make([]float64, numCols),
+	}
+
+	stats := make([]runningStats, numCols)
+	for i := 0; i < numRows; i++ {
+		for j, v := range x.RawRowView(i) {
+			stats[j].add(v)
+		}
+	}
+
+	for j, st := range stats {
+		switch kind {
+		case MinMaxNormalization:
+			s.Offset[j] = st.min
+			s.Scale[j] = st.max - st.min
+		case ZScoreNormalization:
+			s.Offset[j] = st.mean
+			s.Scale[j] = math.Sqrt(st.popVariance())
+		}
+
+		// constant columns are only shifted
+		if s.Scale[j] == 0 {
+			s.Scale[j] = 1
+		}
+	}
+
+	return s
+}
+
+// runningStats accumulates count, range, mean and variance of a stream of
+// values in one pass, using Welford's update for the variance so that it
+// stays accurate for long streams with a large mean.
+type runningStats struct {
+	n        int
+	min, max float64
+	mean, m2 float64
+}
+
+func (st *runningStats) add(v float64) {
+	if st.n == 0 {
+		st.min, st.max = v, v
+	}
+	st.min = math.Min(st.min, v)
+	st.max = math.Max(st.max, v)
+
+	st.n++
+	delta := v - st.mean
+	st.mean += delta / float64(st.n)
+	st.m2 += delta * (v - st.mean)
+}
+
+func (st *runningStats) popVariance() float64 {
+	if st.n == 0 {
+		return 0
+	}
+	return st.m2 / float64(st.n)
+}
+
+func (s *scaler) transform(x mat.Matrix) *mat.Dense {
+	out := new(mat.Dense)
+	s.transformInto(out, x)
+	return out
+}
+
+// transformInto writes the transform of x into dst, which may be empty or
+// already sized like x.
+func (s *scaler) transformInto(dst *mat.Dense, x mat.Matrix) {
+	dst.Apply(func(_, col int, v float64) float64 {
+		return (v - s.Offset[col]) / s.Scale[col]
+	}, x)
+}
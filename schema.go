@@ -0,0 +1,416 @@
+package main
+import (
+	"fmt"
+	"iter"
+	"math"
+	"slices"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+// InputColumns names every column EncodeInput produces, in order. Categorical
+// features contribute one "name=category" column per category other than
+// their Reference, or "name[0]", "name[1]", ... with an Embedding, and Cyclic
+// features a "name=sin" and a "name=cos" column.
+func (ni *NeuralInterface) InputColumns() []string {
+	columns := make([]string, 0)
+
+	for _, def := range ni.InputSchema {
+		switch def.Type {
+		case Categorical:
+			if def.Embedding != nil {
+				for i := 0; i < def.embeddingWidth(); i++ {
+					columns = append(columns, fmt.Sprintf("%s[%d]", def.Name, i))
+				}
+				break
+			}
+			for _, cat := range def.dummyCategories() {
+				columns = append(columns, def.Name+"="+cat)
+			}
+		case Cyclic:
+			columns = append(columns, def.Name+"=sin", def.Name+"=cos")
+		default:
+			columns = append(columns, def.Name)
+		}
+	}
+
+	return columns
+}
+
+func (ni *NeuralInterface) InputWidth() int {
+	return len(ni.InputColumns())
+}
+
+// EmbeddingRows maps pretrained vectors, keyed by Categorical feature and
+// then category, to the input columns they initialize. The result is meant
+// for NetConfig.InitialInputWeights.
+func (ni *NeuralInterface) EmbeddingRows(embeddings map[string]map[string][]float64) (map[int][]float64, error) {
+	index := make(map[string]int)
+	for i, col := range ni.InputColumns() {
+		index[col] = i
+	}
+
+	rows := make(map[int][]float64)
+	for feature, byCategory := range embeddings {
+		for cat, vec := range byCategory {
+			i, ok := index[feature+"="+cat]
+			if !ok {
+				return nil, fmt.Errorf("no input column for category %q of feature %q", cat, feature)
+			}
+			rows[i] = vec
+		}
+	}
+
+	return rows, nil
+}
+
+// OutputColumns names every output column, in order. Categorical outputs
+// contribute one "name=category" column per category.
+func (ni *NeuralInterface) OutputColumns() []string {
+	columns := make([]string, 0, len(ni.OutputSchema))
+	for _, def := range ni.OutputSchema {
+		switch def.Type {
+		case Categorical:
+			for _, cat := range def.Categories {
+				columns = append(columns, def.Name+"="+cat)
+			}
+		default:
+			columns = append(columns, def.Name)
+		}
+	}
+	return columns
+}
+
+func (ni *NeuralInterface) OutputWidth() int {
+	return len(ni.OutputColumns())
+}
+
+// MirrorOutputs replaces OutputSchema with one Continuous output per input
+// column, so that EncodeOutput of the mirrored outputs equals EncodeInput.
+// Continuous inputs are reconstructed under their own name and scaled like
+// the input, so Decode returns them in the input's units; every other
+// column is an unscaled output named after the column.
+func (ni *NeuralInterface) MirrorOutputs() {
+	outputs := make([]OutputDefinition, 0, len(ni.InputSchema))
+	for _, def := range ni.InputSchema {
+		if def.Type == Continuous {
+			outputs = append(outputs, OutputDefinition{Name: def.Name, Type: Continuous, ScaleLike: def.Name})
+			continue
+		}
+		single := &NeuralInterface{InputSchema: []FeatureDefinition{def}}
+		for _, col := range single.InputColumns() {
+			outputs = append(outputs, OutputDefinition{Name: col, Type: Continuous})
+		}
+	}
+	ni.OutputSchema = outputs
+}
+
+// FitCategories replaces the Categories of every Categorical feature with the
+// sorted distinct values seen in data. Fit it on the training split only so
+// held-out categories are not leaked into the encoding.
+func (ni *NeuralInterface) FitCategories(data []TrainingDatum) {
+	for i := range ni.InputSchema {
+		def := &ni.InputSchema[i]
+		if def.Type != Categorical {
+			continue
+		}
+
+		seen := make(map[string]bool)
+		for _, d := range data {
+			if cat, ok := d.Inputs[def.Name].(string); ok {
+				seen[cat] = true
+			}
+		}
+
+		categories := make([]string, 0, len(seen))
+		for cat := range seen {
+			categories = append(categories, cat)
+		}
+		sort.Strings(categories)
+		def.Categories = categories
+	}
+}
+
+// FitContinuousRanges sets Min, Max and Mean of every Continuous feature
+// from the values seen in data, so that EncodeInput scales into [0, 1] and
+// imputes missing values with the training mean rather than the midpoint.
+// Like FitCategories, fit it on the training split only. Features with no
+// values in data are left as they are; a feature constant in data gets Min
+// equal to Max and encodes as 0.5.
+func (ni *NeuralInterface) FitContinuousRanges(data []TrainingDatum) {
+	ni.FitContinuousRangesStream(slices.Values(data))
+}
+
+// FitContinuousRangesStream is FitContinuousRanges over a stream, read once
+// with constant memory, for datasets too large to hold in memory.
+func (ni *NeuralInterface) FitContinuousRangesStream(data iter.Seq[TrainingDatum]) {
+	stats := make(map[string]*runningStats)
+	for _, def := range ni.InputSchema {
+		if def.Type == Continuous {
+			stats[def.Name] = new(runningStats)
+		}
+	}
+
+	for d := range data {
+		for name, st := range stats {
+			if v, ok := d.Inputs[name].(float64); ok {
+				st.add(v)
+			}
+		}
+	}
+
+	for i := range ni.InputSchema {
+		def := &ni.InputSchema[i]
+		st, ok := stats[def.Name]
+		if !ok || st.n == 0 {
+			continue
+		}
+		mean := st.mean
+		def.Min, def.Max, def.Mean = st.min, st.max, &mean
+	}
+}
+
+// FitOutputRanges sets Min and Max of every Continuous output from the
+// targets in data, so that EncodeOutput and Decode rescale with the range
+// the data actually covers. Outputs using ScaleLike, or with no targets in
+// data, are left as they are.
+func (ni *NeuralInterface) FitOutputRanges(data []TrainingDatum) {
+	for i := range ni.OutputSchema {
+		def := &ni.OutputSchema[i]
+		if def.Type != Continuous || def.ScaleLike != "" {
+			continue
+		}
+
+		var st runningStats
+		for _, d := range data {
+			if v, ok := d.Outputs[def.Name]; ok {
+				st.add(v)
+			}
+		}
+		if st.n > 0 {
+			def.Min, def.Max = st.min, st.max
+		}
+	}
+}
+
+// MergeData concatenates several data sources that share most of the
+// schema's features. Each datum keeps only the schema's inputs and outputs;
+// inputs a source lacks are recorded as missing (nil), for EncodeInput to
+// impute. A Categorical or Ordinal value outside the declared Categories
+// (any string for an Embedding, which encodes unknown ones as zeros), or a
+// missing output, is an error naming the source and row.
+func (ni *NeuralInterface) MergeData(sources ...[]TrainingDatum) ([]TrainingDatum, error) {
+	total := 0
+	for _, src := range sources {
+		total += len(src)
+	}
+	merged := make([]TrainingDatum, 0, total)
+
+	for s, src := range sources {
+		for row, d := range src {
+			inputs := make(map[string]interface{}, len(ni.InputSchema))
+			for _, def := range ni.InputSchema {
+				value := d.Inputs[def.Name]
+				if cat, ok := value.(string); ok && (def.Type == Categorical || def.Type == Ordinal) && def.Embedding == nil && !containsString(def.Categories, cat) {
+					return nil, fmt.Errorf("source %d row %d: feature %q has undeclared category %q", s, row, def.Name, cat)
+				}
+				inputs[def.Name] = value
+			}
+
+			outputs := make(map[string]float64, len(ni.OutputSchema))
+			for _, def := range ni.OutputSchema {
+				value, ok := d.Outputs[def.Name]
+				if !ok {
+					return nil, fmt.Errorf("source %d row %d: output %q is missing", s, row, def.Name)
+				}
+				outputs[def.Name] = value
+			}
+
+			merged = append(merged, TrainingDatum{Inputs: inputs, Outputs: outputs})
+		}
+	}
+
+	return merged, nil
+}
+
+// ValidateDatum checks d against the schema before it is encoded: every
+// input present, other than a nil (missing) one, has the Go type its
+// feature expects, and Categorical and Ordinal values are among the
+// declared Categories (any string for an Embedding); every output is
+// present and within its bounds, the Min to Max of a Continuous output with
+// a range, 0 to 1 for Binary and Probability outputs and a category index
+// for Categorical ones. NaN outputs pass as unknown labels. Inputs the
+// schema does not define are ignored, as EncodeInput ignores them.
+func (ni *NeuralInterface) ValidateDatum(d TrainingDatum) error {
+	for _, def := range ni.InputSchema {
+		value := d.Inputs[def.Name]
+		if value == nil {
+			continue
+		}
+
+		switch def.Type {
+		case Binary:
+			if _, ok := value.(bool); !ok {
+				return fmt.Errorf("%w: feature %q expects bool, got %T", ErrBadFeatureType, def.Name, value)
+			}
+		case Continuous, Cyclic:
+			if _, ok := value.(float64); !ok {
+				return fmt.Errorf("%w: feature %q expects float64, got %T", ErrBadFeatureType, def.Name, value)
+			}
+		case Categorical, Ordinal:
+			cat, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("%w: feature %q expects string, got %T", ErrBadFeatureType, def.Name, value)
+			}
+			if def.Embedding == nil && !containsString(def.Categories, cat) {
+				return fmt.Errorf("feature %q has undeclared category %q", def.Name, cat)
+			}
+		}
+	}
+
+	for _, def := range ni.OutputSchema {
+		value, ok := d.Outputs[def.Name]
+		if !ok {
+			return fmt.Errorf("output %q is missing", def.Name)
+		}
+		if math.IsNaN(value) {
+			continue
+		}
+
+		lo, hi := 0.0, 1.0
+		switch def.Type {
+		case Continuous:
+			var err error
+			if lo, hi, err = ni.outputRange(def); err != nil {
+				return err
+			}
+			if lo == hi {
+				continue
+			}
+		case Categorical:
+			if index := int(value); float64(index) != value || index < 0 || index >= len(def.Categories) {
+				return fmt.Errorf("categorical output %q takes a category index below %d, got %v", def.Name, len(def.Categories), value)
+			}
+			continue
+		}
+		if value < lo || value > hi {
+			return fmt.Errorf("output %q is %v, outside [%v, %v]", def.Name, value, lo, hi)
+		}
+	}
+
+	return nil
+}
+
+func containsString(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// OutputQuantiles lists OutputDefinition.Quantile per output column, or nil
+// when no output is a quantile, in the form NetConfig.OutputQuantiles takes.
+func (ni *NeuralInterface) OutputQuantiles() []float64 {
+	quantiles := make([]float64, 0, len(ni.OutputSchema))
+	found := false
+	for _, def := range ni.OutputSchema {
+		for i := 0; i < def.width(); i++ {
+			quantiles = append(quantiles, def.Quantile)
+		}
+		found = found || def.Quantile != 0
+	}
+	if !found {
+		return nil
+	}
+	return quantiles
+}
+
+// ExpandInputs returns a copy of a trained net sized for newNI, a superset
+// of the schema it was trained on. Weights for columns present in oldNI are
+// carried over; columns added by newNI start at zero so the expanded net
+// initially predicts exactly what the old one did. FrozenInputs and
+// InitialInputWeights are renumbered to the new column positions.
+func ExpandInputs(nn *NeuralNet, oldNI, newNI *NeuralInterface) (*NeuralNet, error) {
+	if nn.wHidden == nil || nn.wOut == nil {
+		return nil, fmt.Errorf("%w: the supplied weights are empty", ErrNotTrained)
+	}
+
+	oldColumns := oldNI.InputColumns()
+	if len(oldColumns) != nn.config.InputNeurons {
+		return nil, fmt.Errorf("%w: old schema encodes %d input columns but InputNeurons is %d", ErrDimensionMismatch, len(oldColumns), nn.config.InputNeurons)
+	}
+
+	newColumns := newNI.InputColumns()
+	newIndex := make(map[string]int, len(newColumns))
+	for i, col := range newColumns {
+		newIndex[col] = i
+	}
+	for _, col := range oldColumns {
+		if _, ok := newIndex[col]; !ok {
+			return nil, fmt.Errorf("new schema drops input column %q", col)
+		}
+	}
+
+	// settings indexed by input column follow their column to its new
+	// position
+	remap := func(row int) (int, error) {
+		if row < 0 || row >= len(oldColumns) {
+			return 0, fmt.Errorf("%w: input column %d of %d", ErrDimensionMismatch, row, len(oldColumns))
+		}
+		return newIndex[oldColumns[row]], nil
+	}
+	conf := nn.config
+	conf.InputNeurons = len(newColumns)
+	if nn.config.FrozenInputs != nil {
+		conf.FrozenInputs = make([]int, len(nn.config.FrozenInputs))
+		for i, row := range nn.config.FrozenInputs {
+			newRow, err := remap(row)
+			if err != nil {
+				return nil, fmt.Errorf("frozen inputs: %w", err)
+			}
+			conf.FrozenInputs[i] = newRow
+		}
+	}
+	if nn.config.InitialInputWeights != nil {
+		conf.InitialInputWeights = make(map[int][]float64, len(nn.config.InitialInputWeights))
+		for row, weights := range nn.config.InitialInputWeights {
+			newRow, err := remap(row)
+			if err != nil {
+				return nil, fmt.Errorf("initial input weights: %w", err)
+			}
+			conf.InitialInputWeights[newRow] = weights
+		}
+	}
+	expanded := NewNet(conf)
+
+	expanded.wHidden = mat.NewDense(len(newColumns), conf.HiddenNeurons, nil)
+	for i, col := range oldColumns {
+		expanded.wHidden.SetRow(newIndex[col], nn.wHidden.RawRowView(i))
+	}
+	expanded.bHidden = mat.DenseCopyOf(nn.bHidden)
+	expanded.wOut = mat.DenseCopyOf(nn.wOut)
+	expanded.bOut = mat.DenseCopyOf(nn.bOut)
+	if nn.slopes != nil {
+		expanded.slopes = mat.DenseCopyOf(nn.slopes)
+	}
+
+	if nn.scaler != nil {
+		s := &scaler{
+			Offset: make([]float64, len(newColumns)),
+			Scale:  make([]float64, len(newColumns)),
+		}
+		for i := range s.Scale {
+			s.Scale[i] = 1
+		}
+		for i, col := range oldColumns {
+			s.Offset[newIndex[col]] = nn.scaler.Offset[i]
+			s.Scale[newIndex[col]] = nn.scaler.Scale[i]
+		}
+		expanded.scaler = s
+	}
+
+	return expanded, nil
+}
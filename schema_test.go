@@ -0,0 +1,73 @@
+package main
+import (
+	"slices"
+	"testing"
+)
+
+func TestMergeDataEmbedding(t *testing.T) {
+	ni := &NeuralInterface{
+		InputSchema: []FeatureDefinition{{
+			Name:      "zip",
+			Type:      Categorical,
+			Embedding: map[string][]float64{"10001": {0.1, 0.2}},
+		}},
+		OutputSchema: []OutputDefinition{{Name: "y", Type: Continuous}},
+	}
+	src := []TrainingDatum{
+		{Inputs: map[string]interface{}{"zip": "10001"}, Outputs: map[string]float64{"y": 1}},
+		{Inputs: map[string]interface{}{"zip": "94105"}, Outputs: map[string]float64{"y": 2}},
+	}
+
+	merged, err := ni.MergeData(src)
+	if err != nil {
+		t.Fatalf("MergeData: %v", err)
+	}
+	if len(merged) != 2 {
+		t.Errorf("MergeData kept %d rows, want 2", len(merged))
+	}
+	for i, d := range merged {
+		if err := ni.ValidateDatum(d); err != nil {
+			t.Errorf("row %d: ValidateDatum: %v", i, err)
+		}
+	}
+
+	ni.InputSchema[0].Embedding = nil
+	ni.InputSchema[0].Categories = []string{"10001"}
+	if _, err := ni.MergeData(src); err == nil {
+		t.Error("MergeData accepted a category outside one-hot Categories")
+	}
+}
+
+func TestExpandInputsRemapsColumns(t *testing.T) {
+	oldNI := &NeuralInterface{
+		InputSchema: []FeatureDefinition{
+			{Name: "a", Type: Continuous, Max: 1},
+			{Name: "b", Type: Continuous, Max: 1},
+		},
+		OutputSchema: []OutputDefinition{{Name: "y", Type: Continuous, Max: 1}},
+	}
+	newNI := &NeuralInterface{
+		InputSchema:  append([]FeatureDefinition{{Name: "new", Type: Continuous, Max: 1}}, oldNI.InputSchema...),
+		OutputSchema: oldNI.OutputSchema,
+	}
+	bWeights := []float64{0.3, 0.4}
+	nn := trainedXOR(t, NetConfig{
+		HiddenNeurons:       2,
+		FrozenInputs:        []int{1},
+		InitialInputWeights: map[int][]float64{1: bWeights},
+	})
+
+	expanded, err := ExpandInputs(nn, oldNI, newNI)
+	if err != nil {
+		t.Fatalf("ExpandInputs: %v", err)
+	}
+	if got := expanded.config.FrozenInputs; len(got) != 1 || got[0] != 2 {
+		t.Errorf("FrozenInputs = %v, want [2]", got)
+	}
+	if got := expanded.config.InitialInputWeights; len(got) != 1 || !slices.Equal(got[2], bWeights) {
+		t.Errorf("InitialInputWeights = %v, want column 2 to carry b's weights", got)
+	}
+	if nn.config.FrozenInputs[0] != 1 {
+		t.Errorf("ExpandInputs changed the original net's FrozenInputs to %v", nn.config.FrozenInputs)
+	}
+}
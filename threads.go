@@ -0,0 +1,35 @@
+package main
+import (
+	"runtime"
+	"sync"
+)
+
+// SetMaxThreads caps the worker goroutines gonum's matrix multiplication
+// may use in Train, ContinueTraining and the Predict methods at n; n <= 0
+// lifts the cap. gonum sizes its pool from GOMAXPROCS, so the cap is
+// applied by setting GOMAXPROCS to n once, here, rather than around each
+// call, since changing it stops the world. The setting is process-wide:
+// every goroutine of the process is scheduled on at most n threads until
+// the cap is lifted, which restores the GOMAXPROCS in effect before it was
+// first set. Training itself runs on a single goroutine, so with n set to 1
+// an entire run is sequential and its timings repeatable.
+func SetMaxThreads(n int) {
+	threadCap.Lock()
+	defer threadCap.Unlock()
+
+	switch {
+	case n > 0 && threadCap.prev == 0:
+		threadCap.prev = runtime.GOMAXPROCS(n)
+	case n > 0:
+		runtime.GOMAXPROCS(n)
+	case threadCap.prev != 0:
+		runtime.GOMAXPROCS(threadCap.prev)
+		threadCap.prev = 0
+	}
+}
+
+// threadCap holds the GOMAXPROCS SetMaxThreads replaced, 0 while uncapped.
+var threadCap struct {
+	sync.Mutex
+	prev int
+}
@@ -0,0 +1,32 @@
+package main
+import (
+	"runtime"
+	"testing"
+)
+
+func TestSetMaxThreads(t *testing.T) {
+	prev := runtime.GOMAXPROCS(0)
+	SetMaxThreads(1)
+	defer SetMaxThreads(0)
+
+	var during int
+	nn := trainedXOR(t, NetConfig{NumEpochs: 1, OnBatch: func(_, _ int, _ []int) {
+		during = runtime.GOMAXPROCS(0)
+	}})
+	if during != 1 {
+		t.Errorf("GOMAXPROCS during Train = %d, want 1", during)
+	}
+	x, _ := xorData()
+	if _, err := nn.Predict(x); err != nil {
+		t.Fatalf("Predict: %v", err)
+	}
+	if got := runtime.GOMAXPROCS(0); got != 1 {
+		t.Errorf("GOMAXPROCS after Predict = %d, want the cap of 1 to hold", got)
+	}
+
+	SetMaxThreads(2)
+	SetMaxThreads(0)
+	if got := runtime.GOMAXPROCS(0); got != prev {
+		t.Errorf("GOMAXPROCS after lifting the cap = %d, want %d restored", got, prev)
+	}
+}
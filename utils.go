@@ -3,6 +3,8 @@ import (
 	"gonum.org/v1/gonum/mat"
 	"gonum.org/v1/gonum/floats"
 	"math"
+	"math/rand"
+	"sort"
 	"fmt"
 )
 
@@ -10,8 +12,21 @@ func sigmoid(x float64) float64 {
 	return 1.0 / (1.0 + math.Exp(-x))
 }
 
-func sigmoidPrime(x float64) float64 {
-	return sigmoid(x) * (1.0 - sigmoid(x))
+func softmax(values []float64) []float64 {
+	out := make([]float64, len(values))
+	if len(values) == 0 {
+		return out
+	}
+
+	max := floats.Max(values)
+	var sum float64
+	for i, v := range values {
+		out[i] = math.Exp(v - max)
+		sum += out[i]
+	}
+	floats.Scale(1/sum, out)
+
+	return out
 }
 
 func sumAlongAxis(axis int, m *mat.Dense) (*mat.Dense, error) {
@@ -42,3 +57,270 @@ func sumAlongAxis(axis int, m *mat.Dense) (*mat.Dense, error) {
 
 	return output, nil
 }
+
+// rowClass reduces a target row to a class label: the thresholded value for a
+// single output column, otherwise the index of the largest column.
+func rowClass(y mat.Matrix, row int) int {
+	_, numCols := y.Dims()
+	if numCols == 1 {
+		if y.At(row, 0) >= 0.5 {
+			return 1
+		}
+		return 0
+	}
+	return floats.MaxIdx(mat.Row(nil, row, y))
+}
+
+func classRows(y mat.Matrix) map[int][]int {
+	numRows, _ := y.Dims()
+	groups := make(map[int][]int)
+	for i := 0; i < numRows; i++ {
+		c := rowClass(y, i)
+		groups[c] = append(groups[c], i)
+	}
+	return groups
+}
+
+// balancedRows keeps every row of the rarest class and draws the same number
+// of rows, without replacement, from each other class.
+func balancedRows(y mat.Matrix, randGen *rand.Rand) []int {
+	groups := classRows(y)
+
+	classes := make([]int, 0, len(groups))
+	for c := range groups {
+		classes = append(classes, c)
+	}
+	sort.Ints(classes)
+
+	minority := -1
+	for _, c := range classes {
+		if minority < 0 || len(groups[c]) < minority {
+			minority = len(groups[c])
+		}
+	}
+
+	rows := make([]int, 0, minority*len(classes))
+	for _, c := range classes {
+		members := groups[c]
+		for _, j := range randGen.Perm(len(members))[:minority] {
+			rows = append(rows, members[j])
+		}
+	}
+	sort.Ints(rows)
+
+	return rows
+}
+
+func selectRows(m *mat.Dense, rows []int) *mat.Dense {
+	_, numCols := m.Dims()
+	out := mat.NewDense(len(rows), numCols, nil)
+	for i, r := range rows {
+		out.SetRow(i, m.RawRowView(r))
+	}
+	return out
+}
+
+// splitRows holds out about fraction of the rows of y, returning the kept
+// and held-out row indices in order. The held-out rows are nil when nothing
+// is held out. With stratified, every class with at least two rows puts at
+// least one row on each side.
+func splitRows(y mat.Matrix, fraction float64, stratified bool, randGen *rand.Rand) (keep, holdout []int) {
+	numRows, _ := y.Dims()
+	numHeld := int(float64(numRows) * fraction)
+	if numHeld == 0 || numHeld >= numRows {
+		return nil, nil
+	}
+
+	if !stratified {
+		perm := randGen.Perm(numRows)
+		keep, holdout = perm[numHeld:], perm[:numHeld]
+		sort.Ints(keep)
+		sort.Ints(holdout)
+		return keep, holdout
+	}
+
+	groups := classRows(y)
+	classes := make([]int, 0, len(groups))
+	for c := range groups {
+		classes = append(classes, c)
+	}
+	sort.Ints(classes)
+
+	for _, c := range classes {
+		members := groups[c]
+		n := int(math.Round(float64(len(members)) * fraction))
+		if len(members) >= 2 {
+			n = max(1, min(n, len(members)-1))
+		}
+		for i, j := range randGen.Perm(len(members)) {
+			if i < n {
+				holdout = append(holdout, members[j])
+			} else {
+				keep = append(keep, members[j])
+			}
+		}
+	}
+	if len(keep) == 0 || len(holdout) == 0 {
+		return nil, nil
+	}
+	sort.Ints(keep)
+	sort.Ints(holdout)
+
+	return keep, holdout
+}
+
+// copyParams returns a copy of each parameter's matrix, in order.
+func copyParams(params []namedParam) []*mat.Dense {
+	copies := make([]*mat.Dense, len(params))
+	for i, p := range params {
+		copies[i] = mat.DenseCopyOf(p.m)
+	}
+	return copies
+}
+
+func zeroRow(m *mat.Dense, row int) {
+	r := m.RawRowView(row)
+	for j := range r {
+		r[j] = 0
+	}
+}
+
+// sourceRows maps the rows of a batch drawn by batchRows (nil for all n
+// rows) back through an earlier selection (nil for none) to the rows they
+// came from.
+func sourceRows(batch, selected []int, n int) []int {
+	out := make([]int, n)
+	for i := range out {
+		r := i
+		if batch != nil {
+			r = batch[i]
+		}
+		if selected != nil {
+			r = selected[r]
+		}
+		out[i] = r
+	}
+	return out
+}
+
+// dropColumns zeroes each column of m with probability p and scales the
+// rest by 1/(1-p), keeping every column's expected value. It works in place
+// and returns m.
+func dropColumns(m *mat.Dense, p float64, randGen *rand.Rand) *mat.Dense {
+	_, numCols := m.Dims()
+	mask := make([]float64, numCols)
+	for j := range mask {
+		if randGen.Float64() >= p {
+			mask[j] = 1 / (1 - p)
+		}
+	}
+	m.Apply(func(_, col int, v float64) float64 { return v * mask[col] }, m)
+	return m
+}
+
+// foldRows deals the rows of y into k folds of near-equal size, each in
+// order. With stratified, each class is shuffled and dealt on from where the
+// previous one stopped, so every fold gets its share of every class.
+func foldRows(y mat.Matrix, k int, stratified bool, randGen *rand.Rand) [][]int {
+	numRows, _ := y.Dims()
+	groups := map[int][]int{0: nil}
+	if stratified {
+		groups = classRows(y)
+	} else {
+		for i := 0; i < numRows; i++ {
+			groups[0] = append(groups[0], i)
+		}
+	}
+
+	classes := make([]int, 0, len(groups))
+	for c := range groups {
+		classes = append(classes, c)
+	}
+	sort.Ints(classes)
+
+	folds := make([][]int, k)
+	next := 0
+	for _, c := range classes {
+		members := groups[c]
+		for _, j := range randGen.Perm(len(members)) {
+			folds[next] = append(folds[next], members[j])
+			next = (next + 1) % k
+		}
+	}
+	for _, fold := range folds {
+		sort.Ints(fold)
+	}
+	return folds
+}
+
+// batchRows partitions n rows into shuffled batches of at most size rows:
+// one permutation cut into consecutive slices, so the batches are disjoint
+// and cover every row. A single nil batch stands for the whole set in its
+// original order.
+func batchRows(n, size int, randGen *rand.Rand) [][]int {
+	if size <= 0 || size >= n {
+		return [][]int{nil}
+	}
+
+	perm := randGen.Perm(n)
+	batches := make([][]int, 0, (n+size-1)/size)
+	for start := 0; start < n; start += size {
+		end := start + size
+		if end > n {
+			end = n
+		}
+		batches = append(batches, perm[start:end])
+	}
+	return batches
+}
+
+// gradientNoise accumulates the per-row mean gradients of an epoch's
+// batches to estimate the gradient noise scale: the batch size around
+// which the noise in a batch's gradient matches its signal, so that larger
+// batches stop paying for themselves.
+type gradientNoise struct {
+	sum     []*mat.Dense
+	sumSq   float64
+	batches int
+	rows    int
+}
+
+func (g *gradientNoise) add(grads []*mat.Dense, batchSize int) {
+	if g.sum == nil {
+		g.sum = zerosLike(grads)
+	}
+	scale := 1 / float64(batchSize)
+	for i, grad := range grads {
+		sum := g.sum[i]
+		sum.Apply(func(r, c int, v float64) float64 {
+			return v + scale*grad.At(r, c)
+		}, sum)
+		norm := mat.Norm(grad, 2) * scale
+		g.sumSq += norm * norm
+	}
+	g.batches++
+	g.rows += batchSize
+}
+
+// scale estimates B·tr(Σ)/|G|², with B the mean batch size, tr(Σ) the
+// total variance of the batch gradients and |G|² the squared norm of the
+// true gradient, corrected for the noise in the batches' mean. It is 0
+// with fewer than two batches and +Inf when the noise swamps the signal.
+func (g *gradientNoise) scale() float64 {
+	if g.batches < 2 {
+		return 0
+	}
+	n := float64(g.batches)
+
+	var meanSq float64
+	for _, sum := range g.sum {
+		norm := mat.Norm(sum, 2) / n
+		meanSq += norm * norm
+	}
+	variance := (g.sumSq - n*meanSq) / (n - 1)
+	signal := meanSq - variance/n
+	if signal <= 0 {
+		return math.Inf(1)
+	}
+	return float64(g.rows) / n * variance / signal
+}
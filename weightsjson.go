@@ -0,0 +1,92 @@
+package main
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+type jsonWeights struct {
+	Inputs           int         `json:"inputs"`
+	Hidden           int         `json:"hidden"`
+	Outputs          int         `json:"outputs"`
+	HiddenActivation string      `json:"hiddenActivation"`
+	OutputActivation string      `json:"outputActivation"`
+	Scaler           *jsonScaler `json:"scaler,omitempty"`
+	Layers           []jsonLayer `json:"layers"`
+}
+
+type jsonScaler struct {
+	Offset []float64 `json:"offset"`
+	Scale  []float64 `json:"scale"`
+}
+
+type jsonLayer struct {
+	Name    string      `json:"name"`
+	Weights [][]float64 `json:"weights"`
+	Biases  []float64   `json:"biases"`
+	Slopes  []float64   `json:"slopes,omitempty"`
+}
+
+// ExportWeightsJSON writes the weights as indented JSON for inference
+// outside Go and for diffing nets in version control:
+//
+//	{
+//	  "inputs": 2, "hidden": 4, "outputs": 1,
+//	  "hiddenActivation": "sigmoid", "outputActivation": "none",
+//	  "scaler": {"offset": [...], "scale": [...]},
+//	  "layers": [
+//	    {"name": "hidden", "weights": [[...], ...], "biases": [...]},
+//	    {"name": "output", "weights": [[...], ...], "biases": [...]}
+//	  ]
+//	}
+//
+// Activations are "sigmoid", "none", "relu", "tanh" or "prelu". Each
+// layer's weights hold one row per input to the layer and one column per
+// unit, so a layer computes activation(input·weights + biases) on a row
+// vector; a "prelu" hidden layer also lists its learned negative slopes,
+// one per unit. The scaler is omitted without Normalization; otherwise
+// each input is first mapped to (x - offset) / scale. This reproduces
+// Predict.
+func (nn *NeuralNet) ExportWeightsJSON(w io.Writer) error {
+	if nn.wHidden == nil || nn.wOut == nil {
+		return fmt.Errorf("%w: the supplied weights are empty", ErrNotTrained)
+	}
+
+	hidden := jsonLayer{
+		Name:    "hidden",
+		Weights: jsonRows(nn.wHidden),
+		Biases:  mat.Row(nil, 0, nn.bHidden),
+	}
+	if nn.slopes != nil {
+		hidden.Slopes = mat.Row(nil, 0, nn.slopes)
+	}
+	out := jsonWeights{
+		Inputs:           nn.config.InputNeurons,
+		Hidden:           nn.config.HiddenNeurons,
+		Outputs:          nn.config.OutputNeurons,
+		HiddenActivation: nn.hiddenActivation().name(),
+		OutputActivation: nn.config.OutputActivation.name(),
+		Layers: []jsonLayer{
+			hidden,
+			{Name: "output", Weights: jsonRows(nn.wOut), Biases: mat.Row(nil, 0, nn.bOut)},
+		},
+	}
+	if nn.scaler != nil {
+		out.Scaler = &jsonScaler{Offset: nn.scaler.Offset, Scale: nn.scaler.Scale}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func jsonRows(m *mat.Dense) [][]float64 {
+	numRows, _ := m.Dims()
+	rows := make([][]float64, numRows)
+	for i := range rows {
+		rows[i] = mat.Row(nil, i, m)
+	}
+	return rows
+}